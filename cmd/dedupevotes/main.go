@@ -0,0 +1,32 @@
+// 重复投票清理工具（一次性迁移）
+//
+// 使用方法:
+//
+//	go run ./cmd/dedupevotes
+//
+// 投票表的 (user_id, post_id)、(user_id, comment_id) 唯一索引要求每个用户对同一内容最多一票。
+// 历史数据中存在重复投票时服务拒绝启动，需先运行本工具：每组重复投票只保留最早的一条，
+// 撤销被删除的票带来的积分，并修正受影响帖子和评论的 score。可重复运行，没有重复票时不做任何修改。
+package main
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+	"zhulink/internal/db"
+	"zhulink/internal/services"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, finding env vars from system")
+	}
+
+	db.Connect()
+
+	removed, err := services.DedupeVotes()
+	if err != nil {
+		log.Fatalf("清理重复投票失败: %v", err)
+	}
+	log.Printf("已删除 %d 张重复投票", removed)
+}
@@ -0,0 +1,108 @@
+// 帖子向量补建/迁移工具
+//
+// 使用方法:
+//
+//	go run ./cmd/embeddings                 # 为缺少向量或向量来自其他模型的帖子生成向量
+//	go run ./cmd/embeddings -dry-run        # 只显示当前配置和待处理数量
+//	go run ./cmd/embeddings -adopt          # 把未记录模型的历史向量标记为当前模型生成（不重新计算）
+//	go run ./cmd/embeddings -resize         # 维度变化时改变 posts.embedding 列的维度（清空所有向量）后重建
+//
+// 向量模型由 EMBEDDING_PROVIDER / EMBEDDING_MODEL / EMBEDDING_DIMENSIONS 配置。更换模型后：
+//   - 维度不变：直接运行，逐篇用新模型覆盖；迁移期间语义检索和相关推荐只使用已迁移的帖子
+//   - 维度变化：加 -resize 运行，列类型变更后所有向量清空，再逐篇重建
+//
+// 已完成的帖子记录了当前模型标识，中断（Ctrl+C）后重新运行会从剩余的帖子继续。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	batch := flag.Int("batch", 50, "每批读取的帖子数")
+	limit := flag.Int("limit", 0, "最多处理的帖子数，0 表示全部")
+	dryRun := flag.Bool("dry-run", false, "只显示配置和待处理数量，不生成向量")
+	adopt := flag.Bool("adopt", false, "把未记录模型的历史向量标记为当前模型生成")
+	resize := flag.Bool("resize", false, "列维度与配置不一致时修改列维度（会清空所有向量）")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db.Init()
+	svc := services.GetEmbeddingService()
+	cfg := svc.Config()
+
+	fmt.Println("=== 向量配置 ===")
+	fmt.Printf("模型:     %s\n", svc.ModelID())
+	fmt.Printf("接口:     %s\n", cfg.BaseURL)
+	fmt.Printf("维度:     %d\n", cfg.Dimensions)
+
+	dims, err := db.EmbeddingColumnDimensions()
+	if err != nil {
+		log.Fatalf("读取 posts.embedding 列失败: %v", err)
+	}
+	fmt.Printf("列维度:   %d\n", dims)
+
+	if dims != 0 && dims != cfg.Dimensions {
+		if !*resize {
+			log.Fatalf("posts.embedding 为 %d 维，与配置的 %d 维不一致；确认后使用 -resize 修改列维度（会清空所有向量）", dims, cfg.Dimensions)
+		}
+		if *dryRun {
+			fmt.Println("dry-run：跳过修改列维度")
+			return
+		}
+		fmt.Printf("修改 posts.embedding 为 %d 维并清空向量...\n", cfg.Dimensions)
+		if err := db.ResizeEmbeddingColumn(cfg.Dimensions); err != nil {
+			log.Fatalf("修改列维度失败: %v", err)
+		}
+	}
+
+	if err := svc.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *adopt && !*dryRun {
+		n, err := svc.AdoptLegacyEmbeddings()
+		if err != nil {
+			log.Fatalf("标记历史向量失败: %v", err)
+		}
+		fmt.Printf("已将 %d 篇帖子的历史向量标记为 %s\n", n, svc.ModelID())
+	}
+
+	pending := svc.PendingEmbeddingCount()
+	fmt.Printf("待处理:   %d 篇\n\n", pending)
+	if *dryRun || pending == 0 {
+		return
+	}
+
+	start := time.Now()
+	stats, err := svc.BackfillEmbeddings(ctx, services.EmbeddingBackfillOptions{
+		BatchSize: *batch,
+		Limit:     *limit,
+		Progress: func(done, failed, pending int64) {
+			fmt.Printf("已完成 %d，失败 %d，剩余 %d（耗时 %v）\n", done, failed, pending, time.Since(start).Round(time.Second))
+		},
+	})
+	fmt.Printf("\n完成 %d 篇，失败 %d 篇，耗时 %v\n", stats.Done, stats.Failed, time.Since(start).Round(time.Second))
+	if err != nil {
+		log.Fatalf("向量补建中断: %v（重新运行即可继续）", err)
+	}
+	if stats.Failed > 0 {
+		fmt.Println("失败的帖子可稍后重新运行本工具重试")
+	}
+}
@@ -0,0 +1,391 @@
+// 排名参数回测工具
+//
+// 使用方法:
+//
+//	go run ./cmd/rankbench -days 30 -every 24h -top 30 \
+//	    -config "steep:gravity=1.8" -config "collect_heavy:weight_collect=5,time_base=12"
+//
+// 从数据库读取历史投票、收藏、评论时间线，在每个快照时刻按不同的 RankConfig
+// 重新计算热门榜首页，并输出以下指标（默认表格，-format csv 输出 CSV）：
+//   - churn:    与上一个快照相比首页被替换的比例
+//   - avg_age:  首页帖子的平均年龄（小时）
+//   - overlap:  与基准配置（当前生效的热门参数，即 DefaultConfig 叠加 RANK_HOT_* 环境变量）首页的重合比例
+//
+// -config 中未列出的参数沿用基准配置。
+//
+// 说明：浏览量没有时间线，按帖子发布至今线性增长估算；投票者积分使用当前值，
+// 新账号折扣按投票时的账号年龄计算。工具只读数据库，不会修改任何数据。
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/utils"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// namedConfig 一组待比较的排名参数
+type namedConfig struct {
+	Name   string
+	Config utils.RankConfig
+}
+
+// baseConfig 基准配置，启动时从环境变量加载（与线上热门榜使用同一套参数）
+var baseConfig = utils.DefaultConfig
+
+// configFlags 可重复的 -config 参数，格式 name:key=value,key=value
+type configFlags []namedConfig
+
+func (f *configFlags) String() string {
+	names := make([]string, len(*f))
+	for i, c := range *f {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *configFlags) Set(spec string) error {
+	name, params, ok := strings.Cut(spec, ":")
+	if !ok || name == "" {
+		return fmt.Errorf("配置格式应为 name:key=value,...，实际为 %q", spec)
+	}
+	cfg := baseConfig
+	for _, kv := range strings.Split(params, ",") {
+		key, raw, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return fmt.Errorf("参数格式应为 key=value，实际为 %q", kv)
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("参数 %s 的值不合法: %v", key, err)
+		}
+		switch key {
+		case "gravity":
+			cfg.Gravity = v
+		case "weight_collect":
+			cfg.WeightCollect = v
+		case "weight_comment":
+			cfg.WeightComment = v
+		case "weight_upvote":
+			cfg.WeightUpvote = v
+		case "weight_downvote":
+			cfg.WeightDownvote = v
+		case "weight_view":
+			cfg.WeightView = v
+		case "scale_factor":
+			cfg.ScaleFactor = v
+		case "time_base":
+			cfg.TimeBase = v
+		default:
+			return fmt.Errorf("未知参数 %q", key)
+		}
+	}
+	*f = append(*f, namedConfig{Name: name, Config: cfg})
+	return nil
+}
+
+// postTimeline 单个帖子的互动时间线（各事件按时间升序）
+type postTimeline struct {
+	ID        uint
+	CreatedAt time.Time
+	Views     int
+
+	voteTimes []time.Time
+	upCum     []float64 // 截至第 i 票（含）的加权赞数累计
+	downCum   []float64 // 截至第 i 票（含）的加权踩数累计
+	bookmarks []time.Time
+	comments  []time.Time
+}
+
+// inputAt 还原帖子在 at 时刻的排名输入
+func (p *postTimeline) inputAt(at, now time.Time) utils.RankInput {
+	in := utils.RankInput{CreatedAt: p.CreatedAt, Now: at}
+
+	if n := countBefore(p.voteTimes, at); n > 0 {
+		in.Upvotes = p.upCum[n-1]
+		in.Downvotes = p.downCum[n-1]
+	}
+	in.Collects = countBefore(p.bookmarks, at)
+	in.Comments = countBefore(p.comments, at)
+
+	// 浏览量没有时间线，按发布至今线性增长估算
+	if lifetime := now.Sub(p.CreatedAt); lifetime > 0 {
+		in.Views = int(float64(p.Views) * at.Sub(p.CreatedAt).Seconds() / lifetime.Seconds())
+	}
+	return in
+}
+
+// countBefore 返回有序时间列表中不晚于 at 的事件数
+func countBefore(times []time.Time, at time.Time) int {
+	return sort.Search(len(times), func(i int) bool { return times[i].After(at) })
+}
+
+// result 单个快照、单个配置的指标
+type result struct {
+	Snapshot time.Time
+	Config   string
+	Churn    float64
+	AvgAge   float64
+	Overlap  float64
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, finding env vars from system")
+	}
+	utils.LoadVoteWeights()
+	baseConfig = utils.LoadHotConfig()
+
+	var configs configFlags
+	days := flag.Int("days", 30, "回测最近多少天")
+	every := flag.Duration("every", 24*time.Hour, "快照间隔")
+	top := flag.Int("top", 30, "首页帖子数")
+	maxAge := flag.Duration("max-age", 30*24*time.Hour, "参与排名的帖子最大年龄（更老的帖子视为已沉底）")
+	format := flag.String("format", "table", "输出格式: table 或 csv")
+	flag.Var(&configs, "config", "待比较的配置，格式 name:key=value,...，可重复；key 为 gravity、weight_collect、weight_comment、weight_upvote、weight_downvote、weight_view、scale_factor、time_base")
+	flag.Parse()
+
+	all := append([]namedConfig{{Name: "current", Config: baseConfig}}, configs...)
+
+	conn, err := gorm.Open(postgres.Open(db.DSN()), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -*days)
+	posts, err := loadTimelines(conn, from.Add(-*maxAge), now)
+	if err != nil {
+		log.Fatalf("读取历史数据失败: %v", err)
+	}
+	log.Printf("已加载 %d 篇帖子的互动时间线", len(posts))
+
+	var results []result
+	prev := make(map[string]map[uint]bool)
+	for at := from; !at.After(now); at = at.Add(*every) {
+		fronts := make(map[string][]*postTimeline, len(all))
+		for _, c := range all {
+			fronts[c.Name] = frontPage(posts, utils.HotRanker{Config: c.Config}, at, now, *maxAge, *top)
+		}
+
+		baseline := idSet(fronts["current"])
+		for _, c := range all {
+			front := fronts[c.Name]
+			ids := idSet(front)
+			r := result{Snapshot: at, Config: c.Name, Overlap: overlap(ids, baseline, *top)}
+			if p, ok := prev[c.Name]; ok {
+				r.Churn = 1 - overlap(ids, p, *top)
+			}
+			for _, p := range front {
+				r.AvgAge += at.Sub(p.CreatedAt).Hours()
+			}
+			if len(front) > 0 {
+				r.AvgAge /= float64(len(front))
+			}
+			prev[c.Name] = ids
+			results = append(results, r)
+		}
+	}
+
+	if *format == "csv" {
+		writeCSV(results)
+	} else {
+		writeTable(results, all)
+	}
+}
+
+// loadTimelines 读取 since 之后发布、until 之前的帖子及其投票、收藏、评论时间线
+func loadTimelines(conn *gorm.DB, since, until time.Time) ([]*postTimeline, error) {
+	var posts []*postTimeline
+	if err := conn.Table("posts").Select("id, created_at, views").
+		Where("created_at >= ? AND created_at <= ?", since, until).
+		Order("id").Scan(&posts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*postTimeline, len(posts))
+	ids := make([]uint, len(posts))
+	for i, p := range posts {
+		byID[p.ID] = p
+		ids[i] = p.ID
+	}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	// 投票（不含被判定为刷票的投票），按投票时的账号年龄加权
+	var votes []struct {
+		PostID        uint
+		Value         int
+		CreatedAt     time.Time
+		Points        int
+		UserCreatedAt time.Time
+	}
+	if err := conn.Table("votes").
+		Select("votes.post_id, votes.value, votes.created_at, users.points, users.created_at AS user_created_at").
+		Joins("JOIN users ON users.id = votes.user_id").
+		Where("votes.post_id IN ? AND votes.neutralized = ?", ids, false).
+		Order("votes.created_at").Scan(&votes).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range votes {
+		p := byID[v.PostID]
+		up, down := 0.0, 0.0
+		if n := len(p.upCum); n > 0 {
+			up, down = p.upCum[n-1], p.downCum[n-1]
+		}
+		w := utils.VoteWeightAt(v.Points, v.UserCreatedAt, v.CreatedAt)
+		if v.Value > 0 {
+			up += w
+		} else {
+			down += w
+		}
+		p.voteTimes = append(p.voteTimes, v.CreatedAt)
+		p.upCum = append(p.upCum, up)
+		p.downCum = append(p.downCum, down)
+	}
+
+	var events []struct {
+		PostID    uint
+		CreatedAt time.Time
+	}
+	if err := conn.Table("bookmarks").Select("post_id, created_at").
+		Where("post_id IN ?", ids).Order("created_at").Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		byID[e.PostID].bookmarks = append(byID[e.PostID].bookmarks, e.CreatedAt)
+	}
+
+	events = nil
+	if err := conn.Table("comments").Select("post_id, created_at").
+		Where("post_id IN ?", ids).Order("created_at").Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		byID[e.PostID].comments = append(byID[e.PostID].comments, e.CreatedAt)
+	}
+	return posts, nil
+}
+
+// frontPage 计算 at 时刻按 ranker 排序的首页（分数相同时新帖优先）
+func frontPage(posts []*postTimeline, ranker utils.Ranker, at, now time.Time, maxAge time.Duration, top int) []*postTimeline {
+	type scored struct {
+		post  *postTimeline
+		score float64
+	}
+	candidates := make([]scored, 0, len(posts))
+	for _, p := range posts {
+		if p.CreatedAt.After(at) || at.Sub(p.CreatedAt) > maxAge {
+			continue
+		}
+		candidates = append(candidates, scored{p, ranker.Score(p.inputAt(at, now))})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].post.CreatedAt.After(candidates[j].post.CreatedAt)
+	})
+
+	if len(candidates) > top {
+		candidates = candidates[:top]
+	}
+	front := make([]*postTimeline, len(candidates))
+	for i, c := range candidates {
+		front[i] = c.post
+	}
+	return front
+}
+
+func idSet(posts []*postTimeline) map[uint]bool {
+	set := make(map[uint]bool, len(posts))
+	for _, p := range posts {
+		set[p.ID] = true
+	}
+	return set
+}
+
+// overlap 两个首页共有帖子占首页容量的比例
+func overlap(a, b map[uint]bool, top int) float64 {
+	if top == 0 {
+		return 0
+	}
+	n := 0
+	for id := range a {
+		if b[id] {
+			n++
+		}
+	}
+	return float64(n) / float64(top)
+}
+
+func writeCSV(results []result) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"snapshot", "config", "churn", "avg_age_hours", "overlap_with_current"})
+	for _, r := range results {
+		w.Write([]string{
+			r.Snapshot.Format(time.RFC3339),
+			r.Config,
+			strconv.FormatFloat(r.Churn, 'f', 4, 64),
+			strconv.FormatFloat(r.AvgAge, 'f', 1, 64),
+			strconv.FormatFloat(r.Overlap, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("写出 CSV 失败: %v", err)
+	}
+}
+
+// writeTable 输出每个配置在所有快照上的平均指标
+func writeTable(results []result, configs []namedConfig) {
+	type summary struct {
+		churn, age, overlap float64
+		n, churnN           int
+	}
+	sums := make(map[string]*summary, len(configs))
+	for _, c := range configs {
+		sums[c.Name] = &summary{}
+	}
+	for i, r := range results {
+		s := sums[r.Config]
+		s.age += r.AvgAge
+		s.overlap += r.Overlap
+		s.n++
+		// 第一个快照没有上一期可比，不计入换血率
+		if i >= len(configs) {
+			s.churn += r.Churn
+			s.churnN++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "config\tgravity\ttime_base\tw_collect\tw_comment\tavg_churn\tavg_age_h\toverlap\t")
+	for _, c := range configs {
+		s := sums[c.Name]
+		if s.n == 0 {
+			continue
+		}
+		churn := 0.0
+		if s.churnN > 0 {
+			churn = s.churn / float64(s.churnN)
+		}
+		fmt.Fprintf(w, "%s\t%.2f\t%.1f\t%.1f\t%.1f\t%.1f%%\t%.1f\t%.1f%%\t\n",
+			c.Name, c.Config.Gravity, c.Config.TimeBase, c.Config.WeightCollect, c.Config.WeightComment,
+			churn*100, s.age/float64(s.n), s.overlap/float64(s.n)*100)
+	}
+	w.Flush()
+}
@@ -20,6 +20,7 @@ import (
 	"zhulink/internal/middleware"
 	"zhulink/internal/router"
 	"zhulink/internal/services"
+	"zhulink/internal/utils"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/multitemplate"
@@ -46,12 +47,30 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// 按等级的评论可编辑时长、投票权重和评论审核阈值
+	services.LoadCommentEditWindows()
+	utils.LoadVoteWeights()
+	services.LoadCommentModerationThresholds()
+
 	// Initialize Database
 	db.Init()
 
+	// 校验向量列维度与当前向量模型是否一致，不一致时停用语义检索和相关推荐
+	if err := services.GetEmbeddingService().Validate(); err != nil {
+		log.Printf("[Embedding] %v", err)
+	}
+
+	// 首次启动时写入内置的 LLM 提示词作为第 1 版
+	services.EnsureDefaultPrompts()
+
 	// 初始化 Google OAuth
 	handlers.InitGoogleOAuth()
 
+	// 启动后台任务队列（SEO 元数据与向量、IndexNow、邮件发送）
+	handlers.RegisterJobHandlers()
+	jobQueue := services.GetJobQueue()
+	jobQueue.Start(mainCtx)
+
 	// 初始化异步排名服务
 	rankingSvc := services.GetRankingService()
 
@@ -65,6 +84,20 @@ func main() {
 	rankingSvc.StartScheduledScoreUpdate(mainCtx) // 每天凌晨 3 点更新
 	log.Println("文章分数定时任务已启动: 每天凌晨 3 点更新")
 
+	// 启动刷票分析定时任务
+	services.GetVoteAnalyzer().StartScheduledAnalysis(mainCtx)
+	log.Println("刷票分析定时任务已启动: 每 6 小时执行一次")
+
+	// 启动保存的搜索每日邮件摘要任务
+	services.StartSavedSearchDigest(mainCtx)
+	log.Println("保存的搜索邮件摘要任务已启动: 每天早上 8 点发送")
+
+	// 为历史帖子补建全文索引
+	services.StartSearchIndexBackfill(mainCtx)
+
+	// 启动实时推送的跨实例同步 (Postgres LISTEN/NOTIFY)
+	services.GetLiveHub().StartPGBridge(mainCtx)
+
 	// Initialize Gin
 	r := gin.Default()
 
@@ -95,7 +128,8 @@ func main() {
 	r.Use(sessions.Sessions("zhulink_session", store))
 
 	// Gzip 压缩中间件（对文本类型响应启用压缩）
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	// SSE 实时推送需要逐条刷新，排除在压缩之外
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPathsRegexs([]string{`^/p/[^/]+/live$`})))
 
 	// Load Templates using Multitemplate to avoid collision and allow handler names
 	r.HTMLRender = loadTemplates("./web/templates")
@@ -124,6 +158,8 @@ func main() {
 		IdleTimeout:    120 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
+	// 关闭时先断开 SSE 长连接，否则 srv.Shutdown 会一直等到超时
+	srv.RegisterOnShutdown(services.GetLiveHub().Shutdown)
 
 	// 在 goroutine 中启动服务器
 	go func() {
@@ -140,6 +176,12 @@ func main() {
 	// 停止后台 worker
 	rankingSvc.Shutdown()
 
+	// 取消进行中的 LLM 请求
+	services.GetLLMService().Shutdown()
+
+	// 等待正在执行的后台任务放回队列
+	jobQueue.Shutdown()
+
 	// 给予 5 秒的缓冲时间来处理现有请求
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -290,6 +332,7 @@ func loadTemplates(templatesDir string) multitemplate.Renderer {
 	r.AddFromFilesFuncs("dashboard/overview.html", funcMap, assemble(templatesDir+"/views/dashboard/overview.html")...)
 	r.AddFromFilesFuncs("notification/list.html", funcMap, assemble(templatesDir+"/views/notification/list.html")...)
 	r.AddFromFilesFuncs("dashboard/points.html", funcMap, assemble(templatesDir+"/views/dashboard/points.html")...)
+	r.AddFromFilesFuncs("dashboard/searches.html", funcMap, assemble(templatesDir+"/views/dashboard/searches.html")...)
 	r.AddFromFilesFuncs("dashboard/settings.html", funcMap, assemble(templatesDir+"/views/dashboard/settings.html")...)
 	r.AddFromFilesFuncs("node/list.html", funcMap, assemble(templatesDir+"/views/node/list.html")...)
 	r.AddFromFilesFuncs("search.html", funcMap, assemble(templatesDir+"/views/search.html")...)
@@ -304,6 +347,17 @@ func loadTemplates(templatesDir string) multitemplate.Renderer {
 	r.AddFromFilesFuncs("rss/popular.html", funcMap, assemble(templatesDir+"/views/rss/popular.html")...)
 	r.AddFromFilesFuncs("admin/reports.html", funcMap, assemble(templatesDir+"/views/admin/reports.html")...)
 	r.AddFromFilesFuncs("admin/users.html", funcMap, assemble(templatesDir+"/views/admin/users.html")...)
+	r.AddFromFilesFuncs("admin/comment_revisions.html", funcMap, assemble(templatesDir+"/views/admin/comment_revisions.html")...)
+	r.AddFromFilesFuncs("admin/vote_flags.html", funcMap, assemble(templatesDir+"/views/admin/vote_flags.html")...)
+	r.AddFromFilesFuncs("admin/jobs.html", funcMap, assemble(templatesDir+"/views/admin/jobs.html")...)
+	r.AddFromFilesFuncs("admin/prompts.html", funcMap, assemble(templatesDir+"/views/admin/prompts.html")...)
+	r.AddFromFilesFuncs("admin/prompt_edit.html", funcMap, assemble(templatesDir+"/views/admin/prompt_edit.html")...)
+	r.AddFromFilesFuncs("admin/prompt_dry_run.html", funcMap, templatesDir+"/views/admin/prompt_dry_run.html")
+	r.AddFromFilesFuncs("admin/moderation.html", funcMap, assemble(templatesDir+"/views/admin/moderation.html")...)
+	r.AddFromFilesFuncs("admin/appeals.html", funcMap, assemble(templatesDir+"/views/admin/appeals.html")...)
+	r.AddFromFilesFuncs("story/preview.html", funcMap, templatesDir+"/views/story/preview.html")
+	r.AddFromFilesFuncs("story/thread_digest.html", funcMap, templatesDir+"/views/story/thread_digest.html")
+	r.AddFromFilesFuncs("story/comment_fragment.html", funcMap, append([]string{templatesDir + "/views/story/comment_fragment.html"}, components...)...)
 
 	return r
 }
@@ -8,11 +8,13 @@
 //   - 检查 CF Gateway 配置是否正确
 //   - 测试 GenerateSummary 接口（摘要生成）
 //   - 测试 GenerateSEOMetadata 接口（SEO 元数据生成）
+//   - 测试流式输出
 //
 // 未配置 CF Gateway 时会自动回退到原 LLM 接口（LLM_BASE_URL / LLM_TOKEN）
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -46,23 +48,25 @@ func main() {
 	fmt.Println()
 
 	llm := services.GetLLMService()
+	ctx := context.Background()
 
 	// 测试 GenerateSummary
 	fmt.Println("=== 测试 GenerateSummary ===")
 	start := time.Now()
-	summary, err := llm.GenerateSummary("Go 1.22 发布", "Go 1.22 带来了 range over integers 和新的 for 循环语义。")
+	summary, summaryVersion, err := llm.GenerateSummary(ctx, "Go 1.22 发布", "Go 1.22 带来了 range over integers 和新的 for 循环语义。")
 	elapsed := time.Since(start)
 	if err != nil {
 		fmt.Printf("失败: %v (耗时 %v)\n", err, elapsed)
 	} else {
 		fmt.Printf("成功 (耗时 %v)\n", elapsed)
+		fmt.Printf("提示词版本: %d\n", summaryVersion)
 		fmt.Printf("结果: %s\n\n", truncate(summary, 200))
 	}
 
 	// 测试 GenerateSEOMetadata
 	fmt.Println("=== 测试 GenerateSEOMetadata ===")
 	start = time.Now()
-	seo, err := llm.GenerateSEOMetadata("Go 1.22 发布", "Go 1.22 带来了 range over integers 和新的 for 循环语义。")
+	seo, err := llm.GenerateSEOMetadata(ctx, "Go 1.22 发布", "Go 1.22 带来了 range over integers 和新的 for 循环语义。")
 	elapsed = time.Since(start)
 	if err != nil {
 		fmt.Printf("失败: %v (耗时 %v)\n", err, elapsed)
@@ -70,6 +74,21 @@ func main() {
 		fmt.Printf("成功 (耗时 %v)\n", elapsed)
 		fmt.Printf("Keywords:    %s\n", seo.Keywords)
 		fmt.Printf("Description: %s\n", seo.Description)
+		fmt.Printf("提示词版本:  %d\n", seo.PromptVersion)
+	}
+
+	// 测试流式输出
+	fmt.Println("\n=== 测试 Stream ===")
+	start = time.Now()
+	_, err = llm.Stream(ctx, services.UserPrompt("用一句话回答。", "Go 的 goroutine 是什么？"), func(delta string) error {
+		fmt.Print(delta)
+		return nil
+	})
+	elapsed = time.Since(start)
+	if err != nil {
+		fmt.Printf("\n失败: %v (耗时 %v)\n", err, elapsed)
+	} else {
+		fmt.Printf("\n成功 (耗时 %v)\n", elapsed)
 	}
 }
 
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -12,15 +13,20 @@ import (
 
 var DB *gorm.DB
 
-func Init() {
+// DSN 返回数据库连接串（供需要独立连接的组件复用，如 LISTEN/NOTIFY）
+func DSN() string {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		// Fallback for local dev if not set
 		dsn = "host=localhost user=postgres password=postgres dbname=zhulink port=5432 sslmode=disable TimeZone=Asia/Shanghai"
 	}
+	return dsn
+}
 
+// Connect 建立数据库连接并配置连接池（不执行迁移，供命令行工具使用）
+func Connect() {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(DSN()), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -42,13 +48,22 @@ func Init() {
 		sqlDB.SetConnMaxLifetime(time.Hour) // 连接最大存活时间
 		log.Println("Database connection pool configured: MaxOpen=100, MaxIdle=10, MaxLifetime=1h")
 	}
+}
+
+// Init 连接数据库并执行迁移和初始数据填充
+func Init() {
+	Connect()
+
+	// 建立投票唯一索引前确认没有历史重复票
+	checkDuplicateVotes()
 
 	// Auto Migrate
-	err = DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.User{},
 		&models.Node{},
 		&models.Post{},
 		&models.Comment{},
+		&models.CommentRevision{},
 		&models.Vote{},
 		&models.PointLog{},
 		&models.Bookmark{},
@@ -58,6 +73,15 @@ func Init() {
 		&models.UserSubscription{},
 		&models.FeedItem{},
 		&models.Report{},
+		&models.VoteFlag{},
+		&models.RankingQueueItem{},
+		&models.SavedSearch{},
+		&models.SavedSearchMatch{},
+		&models.Job{},
+		&models.PromptTemplate{},
+		&models.CommentModeration{},
+		&models.PostQuarantine{},
+		&models.ThreadDigest{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
@@ -68,6 +92,87 @@ func Init() {
 	seedNodes()
 }
 
+// checkDuplicateVotes 投票唯一索引尚未建立时检查历史重复票，存在则退出并提示先运行清理工具
+// （索引建立后不会再产生重复票，之后的启动不再检查）
+func checkDuplicateVotes() {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Vote{}) ||
+		(migrator.HasIndex(&models.Vote{}, "idx_votes_user_post") && migrator.HasIndex(&models.Vote{}, "idx_votes_user_comment")) {
+		return
+	}
+	var duplicates int64
+	DB.Raw(`SELECT COUNT(*) FROM (
+		SELECT 1 FROM votes WHERE post_id IS NOT NULL GROUP BY user_id, post_id HAVING COUNT(*) > 1
+		UNION ALL
+		SELECT 1 FROM votes WHERE comment_id IS NOT NULL GROUP BY user_id, comment_id HAVING COUNT(*) > 1
+	) d`).Scan(&duplicates)
+	if duplicates > 0 {
+		log.Fatalf("votes 表中有 %d 组重复投票，无法建立唯一索引，请先运行 go run ./cmd/dedupevotes 清理", duplicates)
+	}
+}
+
+// EmbeddingColumnDimensions 返回 posts.embedding 列声明的向量维度，未声明维度时返回 0
+func EmbeddingColumnDimensions() (int, error) {
+	var typmod int
+	err := DB.Raw(`SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = 'posts'::regclass AND attname = 'embedding' AND NOT attisdropped`).Scan(&typmod).Error
+	if err != nil {
+		return 0, err
+	}
+	if typmod < 0 {
+		return 0, nil
+	}
+	return typmod, nil
+}
+
+// EnsureEmbeddingColumn 新建的 embedding 列没有维度时按 dims 设置，并建立向量索引，返回列的实际维度。
+// 列已有维度时不做修改，与配置不一致由调用方处理（见 ResizeEmbeddingColumn）。
+func EnsureEmbeddingColumn(dims int) (int, error) {
+	current, err := EmbeddingColumnDimensions()
+	if err != nil {
+		return 0, err
+	}
+	if current == 0 {
+		if err := DB.Exec(fmt.Sprintf("ALTER TABLE posts ALTER COLUMN embedding TYPE vector(%d)", dims)).Error; err != nil {
+			return 0, err
+		}
+		current = dims
+	}
+	ensureEmbeddingIndex()
+	return current, nil
+}
+
+// ResizeEmbeddingColumn 把 embedding 列改为 dims 维并清空所有向量（不同维度的向量无法转换），
+// 之后需要重新生成全部向量。向量索引随列重建。
+func ResizeEmbeddingColumn(dims int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range []string{
+			"DROP INDEX IF EXISTS idx_posts_embedding_hnsw",
+			"DROP INDEX IF EXISTS idx_posts_embedding_ivfflat",
+			fmt.Sprintf("ALTER TABLE posts ALTER COLUMN embedding TYPE vector(%d) USING NULL", dims),
+			"UPDATE posts SET embedding_model = '', embedding_dim = 0",
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ensureEmbeddingIndex 为 posts.embedding 建立余弦距离的近似最近邻索引，供语义搜索和相关文章使用。
+// 优先使用 HNSW（pgvector >= 0.5），不支持时退回 IVFFlat。
+func ensureEmbeddingIndex() {
+	err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_posts_embedding_hnsw ON posts USING hnsw (embedding vector_cosine_ops)").Error
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to create HNSW index on posts.embedding, falling back to IVFFlat: %v", err)
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_posts_embedding_ivfflat ON posts USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100)").Error; err != nil {
+		log.Printf("Failed to create IVFFlat index on posts.embedding: %v", err)
+	}
+}
+
 func seedNodes() {
 	// 检查是否已有节点数据
 	var count int64
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"html"
 	"net/http"
 	"strconv"
@@ -12,6 +14,7 @@ import (
 	"zhulink/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AdminHandler struct{}
@@ -180,6 +183,23 @@ func (h *AdminHandler) AdminDeletePost(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// reportView 举报列表项（附带被举报内容的审核状态）
+type reportView struct {
+	models.Report
+	ItemState string
+	FlagScore float64
+}
+
+// moderationStateNames 内容审核状态的展示名称
+var moderationStateNames = map[string]string{
+	models.ModerationVisible:  "正常显示",
+	models.ModerationHidden:   "已自动折叠",
+	models.ModerationApproved: "审核通过",
+	models.ModerationRemoved:  "确认违规",
+
+	models.ModerationQuarantined: "AI 隔离",
+}
+
 // ListReports 举报列表
 func (h *AdminHandler) ListReports(c *gin.Context) {
 	if h.checkAdmin(c) == nil {
@@ -187,16 +207,56 @@ func (h *AdminHandler) ListReports(c *gin.Context) {
 		return
 	}
 
+	status := c.DefaultQuery("status", models.ReportStatusPending)
+
 	var reports []models.Report
-	db.DB.Preload("User").Order("created_at DESC").Find(&reports)
+	db.DB.Preload("User").Where("status = ?", status).Order("created_at DESC").Limit(200).Find(&reports)
+
+	// 批量查询被举报内容的审核状态和举报分
+	var postIDs, commentIDs []uint
+	for _, r := range reports {
+		if r.ItemType == "post" {
+			postIDs = append(postIDs, r.ItemID)
+		} else {
+			commentIDs = append(commentIDs, r.ItemID)
+		}
+	}
+	type itemState struct {
+		ID              uint
+		ModerationState string
+		FlagScore       float64
+	}
+	states := make(map[string]itemState)
+	if len(postIDs) > 0 {
+		var rows []itemState
+		db.DB.Model(&models.Post{}).Select("id, moderation_state, flag_score").Where("id IN ?", postIDs).Scan(&rows)
+		for _, r := range rows {
+			states[fmt.Sprintf("post:%d", r.ID)] = r
+		}
+	}
+	if len(commentIDs) > 0 {
+		var rows []itemState
+		db.DB.Model(&models.Comment{}).Select("id, moderation_state, flag_score").Where("id IN ?", commentIDs).Scan(&rows)
+		for _, r := range rows {
+			states[fmt.Sprintf("comment:%d", r.ID)] = r
+		}
+	}
 
-	// 手动关联被举报内容（由于 Report 表目前没有直接关联结构，需要逻辑处理或 Preload）
-	// 这里简单处理，Preload 已经在 models 里定义了 User
-	// 为了方便展示标题和内容，我们需要根据 ItemType 去查询
+	views := make([]reportView, len(reports))
+	for i, r := range reports {
+		views[i] = reportView{Report: r}
+		if st, ok := states[fmt.Sprintf("%s:%d", r.ItemType, r.ItemID)]; ok {
+			views[i].ItemState = st.ModerationState
+			views[i].FlagScore = st.FlagScore
+		}
+	}
 
 	Render(c, http.StatusOK, "admin/reports.html", gin.H{
 		"Title":       "举报管理",
-		"Reports":     reports,
+		"Reports":     views,
+		"Status":      status,
+		"Threshold":   services.FlagHideThreshold(),
+		"StateNames":  moderationStateNames,
 		"CurrentUser": h.checkAdmin(c),
 	})
 }
@@ -208,13 +268,63 @@ func (h *AdminHandler) HandleReport(c *gin.Context) {
 		return
 	}
 
-	id := c.Param("id")
-	// 这里可以实现删除举报记录或者标记已处理
-	db.DB.Delete(&models.Report{}, id)
+	var report models.Report
+	if err := db.DB.First(&report, c.Param("id")).Error; err != nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	db.DB.Delete(&report)
+
+	// 被删除的举报不再计入举报分
+	services.RecalculateFlagScore(report.ItemType, report.ItemID)
 
 	c.Status(http.StatusOK)
 }
 
+// ApproveReportedItem 审核通过被举报内容：恢复显示，驳回举报并处罚举报者
+func (h *AdminHandler) ApproveReportedItem(c *gin.Context) {
+	h.resolveReportedItem(c, func(r *models.Report) error {
+		_, err := services.ApproveFlaggedItem(r.ItemType, r.ItemID)
+		return err
+	})
+}
+
+// UpholdReportedItem 确认被举报内容违规：保持隐藏，举报标记为成立
+func (h *AdminHandler) UpholdReportedItem(c *gin.Context) {
+	h.resolveReportedItem(c, func(r *models.Report) error {
+		return services.UpholdFlaggedItem(r.ItemType, r.ItemID)
+	})
+}
+
+// resolveReportedItem 按举报记录定位被举报内容并执行审核动作，完成后刷新相关缓存
+func (h *AdminHandler) resolveReportedItem(c *gin.Context, resolve func(r *models.Report) error) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	var report models.Report
+	if err := db.DB.First(&report, c.Param("id")).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if err := resolve(&report); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, "被举报的内容已不存在")
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	invalidateDetailCache(report.ItemPid)
+	invalidateListCaches()
+
+	// 同一内容的多条举报一并处理，刷新整个列表
+	HtmxRedirect(c, "/admin/reports")
+}
+
 // AdminDeleteComment 管理员删除评论
 func (h *AdminHandler) AdminDeleteComment(c *gin.Context) {
 	if h.checkAdmin(c) == nil {
@@ -247,12 +357,48 @@ func (h *AdminHandler) AdminDeleteComment(c *gin.Context) {
 	db.DB.Create(&notification)
 
 	// 3. 软删除：只替换内容
-	comment.Content = "该评论已被管理员删除。"
+	comment.Content = services.CommentAdminDeletedContent
 	db.DB.Save(&comment)
+	invalidateDetailCache(comment.Post.Pid)
+	services.IndexPostAsync(comment.PostID)
+
+	go services.GetLiveHub().Publish(services.LiveEvent{
+		PostID:    comment.PostID,
+		Type:      services.LiveEventCommentDeleted,
+		CommentID: comment.ID,
+	})
 
 	c.Status(http.StatusOK)
 }
 
+// CommentRevisions 查看评论的修订记录
+func (h *AdminHandler) CommentRevisions(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	cid := c.Param("cid")
+	var comment models.Comment
+	if err := db.DB.Preload("Post").Preload("User").Where("cid = ?", cid).First(&comment).Error; err != nil {
+		RenderError(c, http.StatusNotFound, "评论不存在")
+		return
+	}
+
+	var revisions []models.CommentRevision
+	db.DB.Preload("Editor").
+		Where("comment_id = ?", comment.ID).
+		Order("created_at DESC").
+		Find(&revisions)
+
+	Render(c, http.StatusOK, "admin/comment_revisions.html", gin.H{
+		"Title":       "评论修订记录",
+		"Comment":     comment,
+		"Revisions":   revisions,
+		"CurrentUser": h.checkAdmin(c),
+	})
+}
+
 // ListUsers 用户列表（管理员）
 func (h *AdminHandler) ListUsers(c *gin.Context) {
 	if h.checkAdmin(c) == nil {
@@ -288,3 +434,177 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 		"HasPrev":     page > 1,
 	})
 }
+
+// voteFlagView 刷票嫌疑列表项（附带关联账号）
+type voteFlagView struct {
+	models.VoteFlag
+	RelatedUsers []models.User
+}
+
+// voteFlagReasonNames 嫌疑类型的展示名称
+var voteFlagReasonNames = map[string]string{
+	models.VoteFlagReciprocal:        "互赞投票圈",
+	models.VoteFlagSharedFingerprint: "共用设备指纹",
+	models.VoteFlagNewAccount:        "新账号突击投票",
+}
+
+// ListVoteFlags 刷票嫌疑审核列表
+func (h *AdminHandler) ListVoteFlags(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.VoteFlagStatusPending)
+
+	var flags []models.VoteFlag
+	db.DB.Preload("User").Where("status = ?", status).Order("evidence DESC, detected_at DESC").Limit(200).Find(&flags)
+
+	// 批量查询关联账号
+	relatedIDs := make([]uint, 0)
+	for _, f := range flags {
+		for _, part := range strings.Split(f.RelatedUserIDs, ",") {
+			if id, err := strconv.Atoi(part); err == nil {
+				relatedIDs = append(relatedIDs, uint(id))
+			}
+		}
+	}
+	userMap := make(map[string]models.User)
+	if len(relatedIDs) > 0 {
+		var users []models.User
+		db.DB.Where("id IN ?", relatedIDs).Find(&users)
+		for _, u := range users {
+			userMap[strconv.FormatUint(uint64(u.ID), 10)] = u
+		}
+	}
+
+	views := make([]voteFlagView, len(flags))
+	for i, f := range flags {
+		views[i] = voteFlagView{VoteFlag: f}
+		for _, part := range strings.Split(f.RelatedUserIDs, ",") {
+			if u, ok := userMap[part]; ok {
+				views[i].RelatedUsers = append(views[i].RelatedUsers, u)
+			}
+		}
+	}
+
+	Render(c, http.StatusOK, "admin/vote_flags.html", gin.H{
+		"Title":       "刷票审核",
+		"Flags":       views,
+		"Status":      status,
+		"ReasonNames": voteFlagReasonNames,
+		"CurrentUser": h.checkAdmin(c),
+	})
+}
+
+// RunVoteAnalysis 立即执行一次刷票分析
+func (h *AdminHandler) RunVoteAnalysis(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	services.GetVoteAnalyzer().Analyze()
+	HtmxRedirect(c, "/admin/vote-flags")
+}
+
+// DismissVoteFlag 忽略刷票嫌疑
+func (h *AdminHandler) DismissVoteFlag(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	db.DB.Model(&models.VoteFlag{}).Where("id = ?", c.Param("id")).Update("status", models.VoteFlagStatusDismissed)
+	c.Status(http.StatusOK)
+}
+
+// NeutralizeVoteFlag 将嫌疑账号的相关投票从排名计算中剔除
+func (h *AdminHandler) NeutralizeVoteFlag(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	var flag models.VoteFlag
+	if err := db.DB.First(&flag, c.Param("id")).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if _, err := services.NeutralizeFlag(&flag); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// RankingQueueStats 排名更新队列的深度和延迟指标（JSON，供监控采集）
+func (h *AdminHandler) RankingQueueStats(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GetRankingService().Stats())
+}
+
+// jobTypeNames 后台任务类型的展示名称
+var jobTypeNames = map[string]string{
+	services.JobPostMeta:          "SEO 元数据与向量",
+	services.JobIndexNowSubmit:    "IndexNow 提交",
+	services.JobSendMail:          "邮件发送",
+	services.JobSavedSearchDigest: "保存的搜索邮件摘要",
+	services.JobCommentModerate:   "评论审核",
+	services.JobThreadDigest:      "讨论要点",
+}
+
+// ListJobs 后台任务队列：各类任务的数量和按状态筛选的任务列表（默认死信）
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.JobStatusDead)
+	queue := services.GetJobQueue()
+
+	Render(c, http.StatusOK, "admin/jobs.html", gin.H{
+		"Title":       "后台任务",
+		"Stats":       queue.Stats(),
+		"Jobs":        queue.List(status, 200),
+		"Status":      status,
+		"TypeNames":   jobTypeNames,
+		"CurrentUser": h.checkAdmin(c),
+	})
+}
+
+// RetryJob 把死信任务重新放回队列
+func (h *AdminHandler) RetryJob(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := services.GetJobQueue().Retry(uint(id)); err != nil {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// DeleteJob 删除任务（执行中的任务除外）
+func (h *AdminHandler) DeleteJob(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err := services.GetJobQueue().Delete(uint(id)); err != nil {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
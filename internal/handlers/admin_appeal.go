@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAppeals 被 AI 隔离的帖子及申诉（默认申诉中）
+func (h *AdminHandler) ListAppeals(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.QuarantineAppealed)
+
+	var records []models.PostQuarantine
+	db.DB.Preload("Post").Preload("User").
+		Where("status = ?", status).
+		Order("COALESCE(appealed_at, created_at) DESC").Limit(200).Find(&records)
+
+	Render(c, http.StatusOK, "admin/appeals.html", gin.H{
+		"Title":       "申诉处理",
+		"Records":     records,
+		"Status":      status,
+		"CurrentUser": admin,
+	})
+}
+
+// RestoreQuarantinedPost 通过申诉：恢复帖子，解除禁言并退还积分
+func (h *AdminHandler) RestoreQuarantinedPost(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	pid, err := services.RestoreQuarantinedPost(uint(id), admin.ID)
+	if !h.appealResolved(c, err) {
+		return
+	}
+
+	// 恢复后重新生成 SEO 元数据和向量，并重建全文索引
+	var post models.Post
+	if db.DB.Select("id").Where("pid = ?", pid).First(&post).Error == nil {
+		enqueuePostMeta(postMetaJob{PostID: post.ID})
+		services.IndexPostAsync(post.ID)
+	}
+	invalidateDetailCache(pid)
+	invalidateListCaches()
+	HtmxRedirect(c, "/admin/appeals")
+}
+
+// RejectAppeal 驳回申诉，帖子保持隔离
+func (h *AdminHandler) RejectAppeal(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	pid, err := services.RejectAppeal(uint(id), admin.ID)
+	if !h.appealResolved(c, err) {
+		return
+	}
+
+	invalidateDetailCache(pid)
+	HtmxRedirect(c, "/admin/appeals")
+}
+
+// appealResolved 处理申诉操作的错误，返回是否成功
+func (h *AdminHandler) appealResolved(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.String(http.StatusNotFound, "隔离记录不存在")
+	case errors.Is(err, services.ErrAppealResolved):
+		c.String(http.StatusConflict, err.Error())
+	default:
+		c.Status(http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// commentCategoryNames AI 审核违规类别的展示名称
+var commentCategoryNames = map[string]string{
+	models.CommentCategorySpam:     "垃圾广告",
+	models.CommentCategoryAbuse:    "辱骂攻击",
+	models.CommentCategoryOffTopic: "离题灌水",
+}
+
+// ListCommentModerations AI 评论审核队列（默认待处理）
+func (h *AdminHandler) ListCommentModerations(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	status := c.DefaultQuery("status", models.CommentModerationPending)
+
+	var records []models.CommentModeration
+	db.DB.Preload("Comment.User").Preload("Comment.Post").
+		Where("status = ?", status).
+		Order("created_at DESC").Limit(200).Find(&records)
+
+	// 去掉评论开头的回复引用，只展示评论本身
+	for i := range records {
+		_, records[i].Comment.Content = services.SplitReplyPrefix(records[i].Comment.Content)
+	}
+
+	Render(c, http.StatusOK, "admin/moderation.html", gin.H{
+		"Title":         "AI 审核",
+		"Records":       records,
+		"Status":        status,
+		"CategoryNames": commentCategoryNames,
+		"StateNames":    moderationStateNames,
+		"CurrentUser":   admin,
+	})
+}
+
+// ApproveCommentModeration 判定评论正常：恢复显示，驳回该评论的待处理举报
+func (h *AdminHandler) ApproveCommentModeration(c *gin.Context) {
+	h.resolveCommentModeration(c, true)
+}
+
+// UpholdCommentModeration 确认评论违规：对普通用户隐藏
+func (h *AdminHandler) UpholdCommentModeration(c *gin.Context) {
+	h.resolveCommentModeration(c, false)
+}
+
+func (h *AdminHandler) resolveCommentModeration(c *gin.Context, approve bool) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	pid, err := services.ResolveCommentModeration(uint(id), approve)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, "审核记录或评论已不存在")
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	invalidateDetailCache(pid)
+	HtmxRedirect(c, "/admin/moderation")
+}
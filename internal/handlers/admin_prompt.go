@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promptDryRunTimeout 试运行等待模型的时间，须小于 http.Server 的 WriteTimeout
+const promptDryRunTimeout = 18 * time.Second
+
+// promptListItem 提示词列表中的一项
+type promptListItem struct {
+	services.PromptDefinition
+	Active   models.PromptTemplate
+	Versions int
+}
+
+// ListPrompts LLM 提示词列表
+func (h *AdminHandler) ListPrompts(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	items := make([]promptListItem, 0, len(services.PromptDefinitions()))
+	for _, def := range services.PromptDefinitions() {
+		active, _ := services.ActivePromptTemplate(def.Name)
+		items = append(items, promptListItem{
+			PromptDefinition: def,
+			Active:           active,
+			Versions:         len(services.ListPromptVersions(def.Name)),
+		})
+	}
+
+	Render(c, http.StatusOK, "admin/prompts.html", gin.H{
+		"Title":       "提示词",
+		"Prompts":     items,
+		"CurrentUser": admin,
+	})
+}
+
+// EditPrompt 编辑提示词：以启用中的版本（或 ?version= 指定的版本）为底稿，保存为新版本
+func (h *AdminHandler) EditPrompt(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	def, ok := services.GetPromptDefinition(c.Param("name"))
+	if !ok {
+		Render(c, http.StatusNotFound, "error.html", gin.H{"Error": "提示词不存在"})
+		return
+	}
+
+	versions := services.ListPromptVersions(def.Name)
+	draft, _ := services.ActivePromptTemplate(def.Name)
+	if v, err := strconv.Atoi(c.Query("version")); err == nil {
+		for _, version := range versions {
+			if version.Version == v {
+				draft = version
+			}
+		}
+	}
+
+	h.renderPromptEditor(c, http.StatusOK, admin, def, draft, "")
+}
+
+// SavePrompt 保存为新版本，勾选“立即启用”时同时启用
+func (h *AdminHandler) SavePrompt(c *gin.Context) {
+	admin := h.checkAdmin(c)
+	if admin == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	def, ok := services.GetPromptDefinition(c.Param("name"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	system := c.PostForm("system")
+	user := c.PostForm("user")
+	note := strings.TrimSpace(c.PostForm("note"))
+	if len([]rune(note)) > 200 {
+		note = string([]rune(note)[:200])
+	}
+
+	if _, err := services.SavePromptVersion(def.Name, system, user, note, admin.ID, c.PostForm("activate") == "1"); err != nil {
+		draft := models.PromptTemplate{Name: def.Name, System: system, User: user, Note: note}
+		h.renderPromptEditor(c, http.StatusBadRequest, admin, def, draft, "保存失败："+err.Error())
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/prompts/"+def.Name)
+}
+
+// ActivatePrompt 启用指定版本（回滚）
+func (h *AdminHandler) ActivatePrompt(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	name := c.Param("name")
+	version, _ := strconv.Atoi(c.Param("version"))
+	if err := services.ActivatePromptVersion(name, version); err != nil {
+		Render(c, http.StatusBadRequest, "error.html", gin.H{"Error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/prompts/"+name)
+}
+
+// DryRunPrompt 用编辑中的草稿和样例帖子调用一次模型，不保存结果（返回 HTML 片段）
+func (h *AdminHandler) DryRunPrompt(c *gin.Context) {
+	if h.checkAdmin(c) == nil {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	def, ok := services.GetPromptDefinition(c.Param("name"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// 样例帖子：指定 pid，否则使用最新的帖子
+	var post models.Post
+	query := db.DB.Select("id, pid, title, content")
+	if pid := strings.TrimSpace(c.PostForm("sample_pid")); pid != "" {
+		query = query.Where("pid = ?", pid)
+	} else {
+		query = query.Order("created_at DESC")
+	}
+	if err := query.First(&post).Error; err != nil {
+		c.HTML(http.StatusOK, "admin/prompt_dry_run.html", gin.H{"Error": "样例帖子不存在"})
+		return
+	}
+
+	draft := models.PromptTemplate{Name: def.Name, System: c.PostForm("system"), User: c.PostForm("user")}
+	vars := services.PromptVars{"Title": post.Title, "Content": post.Content}
+	data := gin.H{"Post": post}
+
+	// 评论审核：使用样例帖子的最新一条评论
+	if def.Name == services.PromptCommentModeration {
+		var comment models.Comment
+		if err := db.DB.Where("post_id = ?", post.ID).Order("created_at DESC").First(&comment).Error; err != nil {
+			data["Error"] = "样例帖子没有评论"
+			c.HTML(http.StatusOK, "admin/prompt_dry_run.html", data)
+			return
+		}
+		_, vars["Comment"] = services.SplitReplyPrefix(comment.Content)
+		data["Comment"] = comment
+	}
+
+	// 讨论要点：使用样例帖子的全部评论
+	if def.Name == services.PromptThreadDigest {
+		vars["Comments"] = services.ThreadDigestInput(post.ID)
+		if vars["Comments"] == "" {
+			data["Error"] = "样例帖子没有评论"
+			c.HTML(http.StatusOK, "admin/prompt_dry_run.html", data)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), promptDryRunTimeout)
+	defer cancel()
+	result, err := services.GetLLMService().DryRunPrompt(ctx, draft, vars)
+
+	data["Result"] = result
+	if err != nil {
+		data["Error"] = err.Error()
+	}
+	c.HTML(http.StatusOK, "admin/prompt_dry_run.html", data)
+}
+
+func (h *AdminHandler) renderPromptEditor(c *gin.Context, code int, admin *models.User, def services.PromptDefinition, draft models.PromptTemplate, errMsg string) {
+	Render(c, code, "admin/prompt_edit.html", gin.H{
+		"Title":       "编辑提示词 · " + def.Title,
+		"Prompt":      def,
+		"Draft":       draft,
+		"Versions":    services.ListPromptVersions(def.Name),
+		"Usage":       services.PromptVersionUsage(def.Name),
+		"Error":       errMsg,
+		"CurrentUser": admin,
+	})
+}
@@ -8,7 +8,6 @@ import (
 	"zhulink/internal/middleware"
 	"zhulink/internal/models"
 	"zhulink/internal/services"
-	"zhulink/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -67,7 +66,7 @@ func (h *BookmarkHandler) Toggle(c *gin.Context) {
 	}
 
 	// 主动失效详情页缓存
-	utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
+	invalidateDetailCache(post.Pid)
 
 	// 异步更新帖子 Score
 	services.GetRankingService().ScheduleUpdate(postID)
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"zhulink/internal/services"
+)
+
+// commentModerationDebounce 编辑评论后等待的时间，期间再次编辑会重新计时
+const commentModerationDebounce = 30 * time.Second
+
+// commentModerationJob 评论审核任务的参数
+type commentModerationJob struct {
+	CommentID          uint `json:"comment_id"`
+	MatchSavedSearches bool `json:"match_saved_searches,omitempty"` // 审核完成后匹配保存的搜索（被折叠的评论除外）
+}
+
+// enqueueCommentModeration 加入 AI 审核评论的后台任务，评论照常发布，不等待审核结果；
+// 审核通过后再匹配保存的搜索，未配置 LLM 时直接匹配
+func enqueueCommentModeration(commentID uint) {
+	if !services.GetLLMService().Configured() {
+		go services.MatchSavedSearchesForComment(commentID)
+		return
+	}
+	services.EnqueueJob(services.JobCommentModerate, commentModerationJob{CommentID: commentID, MatchSavedSearches: true}, services.JobOptions{
+		DedupeKey: "comment.moderate:" + strconv.FormatUint(uint64(commentID), 10),
+	})
+}
+
+// scheduleCommentModeration 编辑评论后防抖地重新审核。
+// 同键的新评论任务尚未执行时会被本任务替换，因此同样在审核后匹配保存的搜索（匹配记录按评论去重，不会重复提醒）
+func scheduleCommentModeration(commentID uint) {
+	if !services.GetLLMService().Configured() {
+		return
+	}
+	services.EnqueueJob(services.JobCommentModerate, commentModerationJob{CommentID: commentID, MatchSavedSearches: true}, services.JobOptions{
+		DedupeKey: "comment.moderate:" + strconv.FormatUint(uint64(commentID), 10),
+		Delay:     commentModerationDebounce,
+		Debounce:  true,
+	})
+}
+
+// runCommentModerationJob 审核评论，评论被折叠或恢复时刷新详情页缓存；审核成功后匹配保存的搜索
+func runCommentModerationJob(ctx context.Context, payload []byte) error {
+	var job commentModerationJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return services.PermanentJobError(err)
+	}
+
+	pid, changed, err := services.ModerateComment(ctx, job.CommentID)
+	if changed {
+		fmt.Printf("[Async] 评论 %d 的显示状态已被 AI 审核改变\n", job.CommentID)
+		invalidateDetailCache(pid)
+	}
+	if err == nil && job.MatchSavedSearches {
+		services.MatchSavedSearchesForComment(job.CommentID)
+	}
+	return err
+}
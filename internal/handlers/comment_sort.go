@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"zhulink/internal/db"
+	"zhulink/internal/middleware"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 评论排序方式
+const (
+	CommentSortBest          = "best"
+	CommentSortNewest        = "newest"
+	CommentSortOldest        = "oldest"
+	CommentSortControversial = "controversial"
+)
+
+// 评论展示方式
+const (
+	CommentViewFlat     = "flat"
+	CommentViewThreaded = "threaded"
+)
+
+var (
+	commentSortModes = []string{CommentSortBest, CommentSortNewest, CommentSortOldest, CommentSortControversial}
+	commentViewModes = []string{CommentViewFlat, CommentViewThreaded}
+)
+
+// maxCommentIndent 楼中楼最大缩进层级，更深的回复不再继续缩进
+const maxCommentIndent = 4
+
+func isValidCommentSort(mode string) bool {
+	for _, m := range commentSortModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidCommentView(mode string) bool {
+	for _, m := range commentViewModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCommentModes 确定本次请求的评论排序和展示方式
+// 优先级：URL 参数 > 用户偏好 > 默认值（按时间正序、平铺）；URL 参数只影响本次浏览，偏好通过 SaveCommentModes 保存
+func resolveCommentModes(sortParam, viewParam string, user *models.User) (sortMode, viewMode string) {
+	sortMode, viewMode = CommentSortOldest, CommentViewFlat
+	if user != nil {
+		if isValidCommentSort(user.CommentSort) {
+			sortMode = user.CommentSort
+		}
+		if isValidCommentView(user.CommentView) {
+			viewMode = user.CommentView
+		}
+	}
+
+	if isValidCommentSort(sortParam) {
+		sortMode = sortParam
+	}
+	if isValidCommentView(viewParam) {
+		viewMode = viewParam
+	}
+	return sortMode, viewMode
+}
+
+// SaveCommentModes 保存登录用户的评论排序和展示偏好 POST /p/:pid/comment-modes，保存后回到评论区
+func (h *StoryHandler) SaveCommentModes(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+
+	sortMode := c.PostForm("sort")
+	viewMode := c.PostForm("view")
+	if !isValidCommentSort(sortMode) || !isValidCommentView(viewMode) {
+		RenderError(c, http.StatusBadRequest, "无效的排序方式")
+		return
+	}
+
+	if err := db.DB.Model(user).Updates(map[string]interface{}{
+		"comment_sort": sortMode,
+		"comment_view": viewMode,
+	}).Error; err != nil {
+		fmt.Printf("保存评论偏好失败: %v\n", err)
+	}
+
+	target := fmt.Sprintf("/p/%s?%s#comments", url.PathEscape(c.Param("pid")), url.Values{"sort": {sortMode}, "view": {viewMode}}.Encode())
+	if c.GetHeader("HX-Request") == "true" {
+		HtmxRedirect(c, target)
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// fillCommentVotes 批量填充评论的赞/踩数
+func fillCommentVotes(comments []FlatComment) {
+	if len(comments) == 0 {
+		return
+	}
+
+	commentIDs := make([]uint, len(comments))
+	for i, com := range comments {
+		commentIDs[i] = com.ID
+	}
+
+	type VoteResult struct {
+		CommentID uint
+		Up        int
+		Down      int
+	}
+	var results []VoteResult
+	db.DB.Model(&models.Vote{}).
+		Select("comment_id, SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END) as up, SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END) as down").
+		Where("comment_id IN ?", commentIDs).
+		Group("comment_id").
+		Scan(&results)
+
+	voteMap := make(map[uint]VoteResult)
+	for _, r := range results {
+		voteMap[r.CommentID] = r
+	}
+
+	for i := range comments {
+		comments[i].Upvotes = voteMap[comments[i].ID].Up
+		comments[i].Downvotes = voteMap[comments[i].ID].Down
+	}
+}
+
+// sortComments 按指定方式对评论原地排序（稳定排序，同分时保持楼层顺序）
+func sortComments(comments []FlatComment, mode string) {
+	var less func(a, b *FlatComment) bool
+	switch mode {
+	case CommentSortBest:
+		less = func(a, b *FlatComment) bool {
+			return utils.WilsonLowerBound(a.Upvotes, a.Downvotes) > utils.WilsonLowerBound(b.Upvotes, b.Downvotes)
+		}
+	case CommentSortControversial:
+		less = func(a, b *FlatComment) bool {
+			return utils.ControversyScore(a.Upvotes, a.Downvotes) > utils.ControversyScore(b.Upvotes, b.Downvotes)
+		}
+	case CommentSortNewest:
+		less = func(a, b *FlatComment) bool {
+			return a.Floor > b.Floor
+		}
+	default:
+		less = func(a, b *FlatComment) bool {
+			return a.Floor < b.Floor
+		}
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return less(&comments[i], &comments[j])
+	})
+}
+
+// arrangeComments 按展示方式和排序方式组织评论
+// 平铺：整体排序；楼中楼：同级回复各自排序后深度优先展开，并记录层级用于缩进
+func arrangeComments(comments []FlatComment, sortMode, viewMode string) []FlatComment {
+	if viewMode != CommentViewThreaded {
+		sortComments(comments, sortMode)
+		return comments
+	}
+
+	exists := make(map[uint]bool, len(comments))
+	for _, com := range comments {
+		exists[com.ID] = true
+	}
+
+	// 父评论不存在（如已被物理删除）的回复作为顶层评论展示
+	children := make(map[uint][]FlatComment)
+	var roots []FlatComment
+	for _, com := range comments {
+		if com.ParentID != nil && exists[*com.ParentID] {
+			children[*com.ParentID] = append(children[*com.ParentID], com)
+		} else {
+			roots = append(roots, com)
+		}
+	}
+
+	arranged := make([]FlatComment, 0, len(comments))
+	var walk func(nodes []FlatComment, depth int)
+	walk = func(nodes []FlatComment, depth int) {
+		sortComments(nodes, sortMode)
+		for _, node := range nodes {
+			node.Depth = depth
+			if depth > maxCommentIndent {
+				node.Depth = maxCommentIndent
+			}
+			arranged = append(arranged, node)
+			walk(children[node.ID], depth+1)
+		}
+	}
+	walk(roots, 0)
+
+	return arranged
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/middleware"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+	"zhulink/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveHeartbeatInterval SSE 心跳间隔，防止反向代理因空闲断开连接
+const liveHeartbeatInterval = 25 * time.Second
+
+// Live 文章详情页的 SSE 实时推送（新评论、删除评论、票数变化）
+func (h *StoryHandler) Live(c *gin.Context) {
+	pid := c.Param("pid")
+
+	var post models.Post
+	if err := db.DB.Select("id, user_id, moderation_state").Where("pid = ?", pid).First(&post).Error; err != nil ||
+		!canViewPost(post, currentLiveUser(c)) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// 长连接不受 http.Server 的 WriteTimeout 限制
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	hub := services.GetLiveHub()
+	events, unsubscribe := hub.Subscribe(post.ID)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// 立即输出一次，让客户端确认连接已建立
+	c.SSEvent("ping", "")
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-hub.Done():
+			return false
+		case ev := <-events:
+			c.SSEvent(ev.Type, ev)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", "")
+			return true
+		}
+	})
+}
+
+// CommentFragment 渲染单条评论，供详情页收到新评论事件后插入
+func (h *StoryHandler) CommentFragment(c *gin.Context) {
+	pid := c.Param("pid")
+	commentID, _ := strconv.Atoi(c.Param("id"))
+
+	var comment models.Comment
+	currentUser := currentLiveUser(c)
+	if err := db.DB.Preload("User").Preload("Post").First(&comment, commentID).Error; err != nil ||
+		comment.Post.Pid != pid || !canViewPost(comment.Post, currentUser) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	// 楼层号按发布时间计算，与详情页保持一致
+	var floor int64
+	db.DB.Model(&models.Comment{}).Where("post_id = ? AND created_at <= ?", comment.PostID, comment.CreatedAt).Count(&floor)
+
+	_, editBody := services.SplitReplyPrefix(comment.Content)
+	flat := []FlatComment{{
+		Comment:     comment,
+		ContentHTML: utils.RenderMarkdown(comment.Content),
+		EditBody:    editBody,
+		Floor:       int(floor),
+	}}
+	fillCommentVotes(flat)
+
+	Render(c, http.StatusOK, "story/comment_fragment.html", gin.H{
+		"Comment":          flat[0],
+		"EditableComments": editableCommentIDs(flat, currentUser),
+	})
+}
+
+// currentLiveUser 返回当前登录用户，未登录时为 nil
+func currentLiveUser(c *gin.Context) *models.User {
+	if user, exists := c.Get(middleware.CheckUserKey); exists && user != nil {
+		return user.(*models.User)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// postMetaDebounce 编辑后等待的时间，期间再次编辑会重新计时，只按最后一版重新生成
+const postMetaDebounce = time.Minute
+
+// postMetaRetryWindow 详情页发现元数据缺失时，同一帖子在该时间内只入队一次，避免每次浏览都写库
+const postMetaRetryWindow = 10 * time.Minute
+
+var (
+	postMetaEnqueuedMu sync.Mutex
+	postMetaEnqueued   = make(map[uint]time.Time)
+)
+
+// postMetaJob 帖子元数据任务的参数
+type postMetaJob struct {
+	PostID             uint `json:"post_id"`
+	Edited             bool `json:"edited,omitempty"`               // 编辑触发：内容与上次生成时不同才重新生成
+	MatchSavedSearches bool `json:"match_saved_searches,omitempty"` // 新帖：完成后匹配保存的搜索（广告贴除外）
+}
+
+// RegisterJobHandlers 注册 handlers 中实现的后台任务，须在任务队列启动前调用
+func RegisterJobHandlers() {
+	h := NewStoryHandler()
+	services.GetJobQueue().Register(services.JobPostMeta, h.runPostMetaJob, services.JobTypeConfig{})
+	services.GetJobQueue().Register(services.JobCommentModerate, runCommentModerationJob, services.JobTypeConfig{Timeout: time.Minute})
+}
+
+// postContentHash 标题+正文的哈希，忽略空白差异（只改了空格、换行不算实质修改）
+func postContentHash(title, content string) string {
+	normalized := strings.Join(strings.Fields(title), " ") + "\n" + strings.Join(strings.Fields(content), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// enqueuePostMeta 加入生成 SEO 元数据和向量的后台任务，同一帖子只保留一个等待中的任务
+func enqueuePostMeta(job postMetaJob) {
+	services.EnqueueJob(services.JobPostMeta, job, services.JobOptions{
+		DedupeKey: "post.meta:" + strconv.FormatUint(uint64(job.PostID), 10),
+	})
+}
+
+// schedulePostMetaRefresh 编辑后防抖地重新审核并生成 SEO 元数据和向量
+func schedulePostMetaRefresh(postID uint) {
+	services.EnqueueJob(services.JobPostMeta, postMetaJob{PostID: postID, Edited: true}, services.JobOptions{
+		DedupeKey: "post.meta.edit:" + strconv.FormatUint(uint64(postID), 10),
+		Delay:     postMetaDebounce,
+		Debounce:  true,
+	})
+}
+
+// postMetaMissing 帖子缺少 SEO 描述或向量，且对应服务可用。
+// 隔离中的帖子不补全；有哈希但没有描述表示已恢复的帖子再次被判定为广告，不再重复生成。
+func postMetaMissing(post models.Post) bool {
+	if post.ModerationState == models.ModerationQuarantined {
+		return false
+	}
+	if post.SEODescription == "" && post.MetaHash == "" && services.GetLLMService().Configured() {
+		return true
+	}
+	missingVector := post.Embedding == nil || len(post.Embedding.Slice()) == 0
+	return missingVector && services.GetEmbeddingService().Enabled()
+}
+
+// enqueueMissingPostMeta 详情页补全缺失的元数据，同一帖子在 postMetaRetryWindow 内只入队一次
+func enqueueMissingPostMeta(postID uint) {
+	postMetaEnqueuedMu.Lock()
+	now := time.Now()
+	if at, ok := postMetaEnqueued[postID]; ok && now.Sub(at) < postMetaRetryWindow {
+		postMetaEnqueuedMu.Unlock()
+		return
+	}
+	for id, at := range postMetaEnqueued {
+		if now.Sub(at) >= postMetaRetryWindow {
+			delete(postMetaEnqueued, id)
+		}
+	}
+	postMetaEnqueued[postID] = now
+	postMetaEnqueuedMu.Unlock()
+
+	enqueuePostMeta(postMetaJob{PostID: postID})
+}
+
+// runPostMetaJob 读取帖子最新内容生成元数据。编辑触发时与上次生成时的哈希相同则跳过（编辑后又改回原样）
+func (h *StoryHandler) runPostMetaJob(ctx context.Context, payload []byte) error {
+	var job postMetaJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return services.PermanentJobError(err)
+	}
+
+	var post models.Post
+	if err := db.DB.First(&post, job.PostID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 帖子已删除
+		}
+		return err
+	}
+
+	// 隔离中的帖子等待申诉处理，恢复后再生成
+	if post.ModerationState == models.ModerationQuarantined {
+		return nil
+	}
+
+	regenerateSEO := post.SEODescription == "" && post.MetaHash == ""
+	if job.Edited && post.MetaHash != postContentHash(post.Title, post.Content) {
+		fmt.Printf("[Async] 帖子 %d 内容已修改，重新审核并生成 SEO 和向量数据\n", post.ID)
+		regenerateSEO = true
+		// 管理员的恢复只针对当时的内容，修改后撤销，重新接受 AI 审核
+		if post.ModerationState == models.ModerationApproved {
+			if err := db.DB.Model(&models.Post{}).
+				Where("id = ? AND moderation_state = ?", post.ID, models.ModerationApproved).
+				Update("moderation_state", models.ModerationVisible).Error; err != nil {
+				return err
+			}
+			post.ModerationState = models.ModerationVisible
+		}
+	}
+
+	adPost, err := h.generatePostMeta(ctx, post, regenerateSEO)
+	if job.MatchSavedSearches && !adPost {
+		// 匹配记录按帖子去重，重试时不会重复提醒
+		services.MatchSavedSearchesForPost(post.ID)
+	}
+	return err
+}
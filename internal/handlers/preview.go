@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"zhulink/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewMaxLength 预览内容的最大长度（字节）
+const previewMaxLength = 100 * 1024
+
+// Preview 使用服务端渲染流程预览 Markdown 草稿（文章与评论编辑器共用）
+// 返回渲染后的 HTML 以及渲染过程中的问题提示
+func (h *StoryHandler) Preview(c *gin.Context) {
+	content := c.PostForm("content")
+	if len(content) > previewMaxLength {
+		c.String(http.StatusRequestEntityTooLarge, "内容过长，无法预览")
+		return
+	}
+
+	html, warnings := utils.RenderMarkdownPreview(content)
+	c.HTML(http.StatusOK, "story/preview.html", gin.H{
+		"Empty":    content == "",
+		"HTML":     html,
+		"Warnings": warnings,
+	})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+	"zhulink/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rankTab 列表页顶部的排序切换项
+type rankTab struct {
+	Name  string
+	Label string
+	Path  string
+}
+
+// rankTabs 按启用的排名算法生成排序切换项（热门对应首页），最后是时间窗口榜单
+func rankTabs() []rankTab {
+	rankers := services.GetRankingService().Rankers()
+	tabs := make([]rankTab, 0, len(rankers))
+	for _, r := range rankers {
+		path := "/" + r.Name()
+		if r.Name() == utils.RankerHot {
+			path = "/"
+		}
+		tabs = append(tabs, rankTab{Name: r.Name(), Label: r.Label(), Path: path})
+	}
+	// 不衰减的时间窗口榜单
+	tabs = append(tabs, rankTab{Name: "top_window", Label: "时间榜", Path: "/top"})
+	return tabs
+}
+
+// rankDescriptions 各排名列表的 SEO 描述
+var rankDescriptions = map[string]string{
+	utils.RankerRising:        "ZhuLink 社区近两天互动增长最快的新内容，发现正在升温的讨论。",
+	utils.RankerControversial: "ZhuLink 社区赞同与反对最接近的讨论，看看大家在争论什么。",
+	utils.RankerBest:          "ZhuLink 社区历史上最受认可的内容，不随时间衰减。",
+}
+
+// ListRanked 按指定排名算法的持久化分数列出帖子（热门榜由首页 ListTop 负责）
+func (h *StoryHandler) ListRanked(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ranker, ok := services.GetRankingService().Ranker(name)
+		if !ok {
+			RenderError(c, http.StatusNotFound, "您访问的页面不存在")
+			return
+		}
+		if ranker.Name() == utils.RankerHot {
+			c.Redirect(http.StatusMovedPermanently, "/")
+			return
+		}
+
+		// 分页参数
+		page := 1
+		if p := c.Query("page"); p != "" {
+			if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+				page = pageNum
+			}
+		}
+
+		cacheKey := fmt.Sprintf("story:rank:%s:page:%d", ranker.Name(), page)
+		if cachedData := utils.GetCache().Get(cacheKey); cachedData != nil {
+			if hData, ok := cachedData.(gin.H); ok {
+				Render(c, http.StatusOK, "story/list.html", hData)
+				return
+			}
+		}
+
+		perPage := 30
+		offset := (page - 1) * perPage
+
+		// 分数为 0 的帖子不属于该榜单（如超出飙升时间窗口、没有争议）
+		column := ranker.Column()
+		listed := db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed).Where(column + " > 0").
+			Session(&gorm.Session{})
+
+		var total int64
+		listed.Count(&total)
+
+		totalPages := int(math.Ceil(float64(total) / float64(perPage)))
+		if totalPages == 0 {
+			totalPages = 1
+		}
+
+		var posts []models.Post
+		listed.Preload("User").Preload("Node").
+			Order(column + " DESC, created_at DESC").
+			Limit(perPage).
+			Offset(offset).
+			Find(&posts)
+
+		fillCommentCounts(posts)
+
+		// 获取节点列表（用于侧边栏导航）
+		var nodes []models.Node
+		db.DB.Order("id ASC").Find(&nodes)
+
+		// SEO 数据
+		siteURL := os.Getenv("SITE_URL")
+		if siteURL == "" {
+			siteURL = "https://zhulink.vip"
+		}
+		fullURL := fmt.Sprintf("%s/%s", siteURL, ranker.Name())
+		if page > 1 {
+			fullURL = fmt.Sprintf("%s/%s?page=%d", siteURL, ranker.Name(), page)
+		}
+
+		renderData := gin.H{
+			"Posts":       posts,
+			"Nodes":       nodes,
+			"Active":      ranker.Name(),
+			"Title":       ranker.Label(),
+			"CurrentPage": page,
+			"TotalPages":  totalPages,
+			"Description": rankDescriptions[ranker.Name()],
+			"Keywords":    fmt.Sprintf("ZhuLink, 竹林, %s, 技术社区, 高质量内容", ranker.Label()),
+			"FullURL":     fullURL,
+			"RankTabs":    rankTabs(),
+			"Ranker":      ranker.Name(),
+		}
+
+		// 写入缓存，有效期 1 分钟
+		utils.GetCache().Set(cacheKey, renderData, 1*time.Minute)
+
+		Render(c, http.StatusOK, "story/list.html", renderData)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"zhulink/internal/db"
 	"zhulink/internal/middleware"
@@ -229,13 +230,9 @@ func (h *RSSHandler) GetItems(c *gin.Context) {
 	}
 
 	// 包装已读状态
-	type ItemWithReadStatus struct {
-		Item   models.FeedItem
-		IsRead bool
-	}
-	var itemsWithStatus []ItemWithReadStatus
+	var itemsWithStatus []rssItemView
 	for _, item := range items {
-		itemsWithStatus = append(itemsWithStatus, ItemWithReadStatus{
+		itemsWithStatus = append(itemsWithStatus, rssItemView{
 			Item:   item,
 			IsRead: subscription.IsItemRead(item.ID, item.PublishedAt),
 		})
@@ -263,6 +260,116 @@ func (h *RSSHandler) GetItems(c *gin.Context) {
 	})
 }
 
+// rssItemView 文章列表中的一项
+type rssItemView struct {
+	Item      models.FeedItem
+	IsRead    bool
+	FeedTitle string // 搜索结果来自多个订阅源，需要显示所属订阅源
+}
+
+// rssItemReadSQL 文章对当前用户是否已读（水位线之下或在例外集中），需要关联 user_subscriptions us
+const rssItemReadSQL = `((us.last_read_anchor IS NOT NULL AND feed_items.published_at <= us.last_read_anchor)
+	OR COALESCE(NULLIF(us.read_exceptions, '')::jsonb @> to_jsonb(feed_items.id), false))`
+
+// maxRSSSearchTerms 搜索词数量上限，避免过长的查询拖慢数据库
+const maxRSSSearchTerms = 8
+
+// SearchItems HTMX 接口，在用户订阅的订阅源中搜索文章（标题、摘要、正文），支持分类和已读状态过滤
+func (h *RSSHandler) SearchItems(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+	query := strings.TrimSpace(c.Query("q"))
+	category := c.Query("category")
+	readFilter := c.DefaultQuery("read", "all") // all | unread | read
+	isAppend := c.Query("append") == "true"
+	pageSize := 30
+
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		c.String(http.StatusBadRequest, "请输入搜索关键词")
+		return
+	}
+	if len(terms) > maxRSSSearchTerms {
+		terms = terms[:maxRSSSearchTerms]
+	}
+
+	// 用户的订阅，用于计算已读状态和显示订阅源名称
+	var subscriptions []models.UserSubscription
+	db.DB.Preload("Feed").Where("user_id = ?", user.ID).Find(&subscriptions)
+	subByFeed := make(map[uint]*models.UserSubscription, len(subscriptions))
+	for i := range subscriptions {
+		subByFeed[subscriptions[i].FeedID] = &subscriptions[i]
+	}
+
+	// 只在当前用户订阅的订阅源中搜索
+	itemQuery := db.DB.Model(&models.FeedItem{}).
+		Joins("JOIN user_subscriptions us ON us.feed_id = feed_items.feed_id AND us.user_id = ?", user.ID)
+	if category != "" {
+		itemQuery = itemQuery.Where("us.category = ?", category)
+	}
+	for _, term := range terms {
+		pattern := "%" + utils.EscapeLike(term) + "%"
+		itemQuery = itemQuery.Where("(feed_items.title ILIKE ? OR feed_items.description ILIKE ? OR feed_items.content ILIKE ?)",
+			pattern, pattern, pattern)
+	}
+	switch readFilter {
+	case "unread":
+		itemQuery = itemQuery.Where("NOT " + rssItemReadSQL)
+	case "read":
+		itemQuery = itemQuery.Where(rssItemReadSQL)
+	default:
+		readFilter = "all"
+	}
+
+	// 分页游标：使用 published_at（与订阅源文章列表一致）
+	if lastPublishedAtStr := c.Query("last_published_at"); lastPublishedAtStr != "" {
+		if t, err := time.Parse(time.RFC3339, lastPublishedAtStr); err == nil {
+			itemQuery = itemQuery.Where("feed_items.published_at < ?", t)
+		}
+	}
+
+	var items []models.FeedItem
+	itemQuery.Select("feed_items.*").
+		Order("feed_items.published_at DESC").
+		Limit(pageSize + 1).
+		Find(&items)
+
+	hasMore := len(items) > pageSize
+	if hasMore {
+		items = items[:pageSize]
+	}
+
+	var itemsWithStatus []rssItemView
+	for _, item := range items {
+		view := rssItemView{Item: item}
+		if sub, ok := subByFeed[item.FeedID]; ok {
+			view.IsRead = sub.IsItemRead(item.ID, item.PublishedAt)
+			view.FeedTitle = sub.GetDisplayTitle()
+		}
+		itemsWithStatus = append(itemsWithStatus, view)
+	}
+
+	var nextPublishedAt string
+	if len(items) > 0 {
+		nextPublishedAt = items[len(items)-1].PublishedAt.Format(time.RFC3339)
+	}
+
+	templateName := "rss/item_list.html"
+	if isAppend {
+		templateName = "rss/item_list_items.html"
+	}
+
+	c.HTML(http.StatusOK, templateName, gin.H{
+		"Items":           itemsWithStatus,
+		"Search":          true,
+		"Query":           query,
+		"ReadFilter":      readFilter,
+		"Category":        category,
+		"HasMore":         hasMore,
+		"LastPublishedAt": nextPublishedAt,
+		"ShowAll":         readFilter != "unread",
+	})
+}
+
 // 辅助函数：压缩已读例外集并推进水位线
 func compressExceptions(sub *models.UserSubscription) {
 	if len(sub.ReadExceptions) == 0 {
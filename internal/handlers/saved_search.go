@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"zhulink/internal/middleware"
+	"zhulink/internal/models"
+	"zhulink/internal/searchquery"
+	"zhulink/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SavedSearchHandler struct{}
+
+func NewSavedSearchHandler() *SavedSearchHandler {
+	return &SavedSearchHandler{}
+}
+
+// savedSearchView 保存的搜索及其在搜索页的链接
+type savedSearchView struct {
+	models.SavedSearch
+	SearchParams string
+}
+
+// List 我保存的搜索
+func (h *SavedSearchHandler) List(c *gin.Context) {
+	h.render(c, http.StatusOK, "")
+}
+
+// Create 保存搜索页当前的检索条件（由搜索页表单以 POST 提交，参数与 GET /search 相同）
+func (h *SavedSearchHandler) Create(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+
+	query := searchquery.Parse(c.PostForm("q"))
+	for _, key := range searchquery.FilterKeys {
+		if value := c.PostForm(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	if _, err := services.CreateSavedSearch(user.ID, query, false); err != nil {
+		message := "保存失败，请稍后重试"
+		if errors.Is(err, services.ErrSavedSearchEmpty) || errors.Is(err, services.ErrSavedSearchLimit) {
+			message = err.Error()
+		} else {
+			fmt.Printf("[SavedSearch] 保存搜索失败 (user_id=%d, q=%q): %v\n", user.ID, query.String(), err)
+		}
+		h.render(c, http.StatusBadRequest, message)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/dashboard/searches")
+}
+
+// Delete 删除保存的搜索
+func (h *SavedSearchHandler) Delete(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DeleteSavedSearch(user.ID, uint(id)); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Redirect(http.StatusFound, "/dashboard/searches")
+}
+
+// ToggleDigest 开启或关闭每日邮件摘要
+func (h *SavedSearchHandler) ToggleDigest(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	enabled := c.PostForm("enabled") == "1"
+	if err := services.SetSavedSearchDigest(user.ID, uint(id), enabled); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Redirect(http.StatusFound, "/dashboard/searches")
+}
+
+// render 渲染保存的搜索列表，message 非空时显示错误提示
+func (h *SavedSearchHandler) render(c *gin.Context, code int, message string) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+
+	searches, err := services.ListSavedSearches(user.ID)
+	if err != nil {
+		fmt.Printf("[SavedSearch] 加载保存的搜索失败 (user_id=%d): %v\n", user.ID, err)
+	}
+	views := make([]savedSearchView, len(searches))
+	for i, s := range searches {
+		views[i] = savedSearchView{
+			SavedSearch:  s,
+			SearchParams: searchquery.Parse(s.Query).Values().Encode(),
+		}
+	}
+
+	Render(c, code, "dashboard/searches.html", gin.H{
+		"Title":    "保存的搜索",
+		"Searches": views,
+		"Limit":    services.MaxSavedSearchesPerUser,
+		"Error":    message,
+	})
+}
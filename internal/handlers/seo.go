@@ -150,7 +150,7 @@ func (h *SEOHandler) SitemapXML(c *gin.Context) {
 
 	// 7. 最近的文章详情页(限制500篇,避免sitemap过大)
 	var posts []models.Post
-	db.DB.Order("created_at DESC").Limit(500).Find(&posts)
+	db.DB.Scopes(models.PubliclyListed).Order("created_at DESC").Limit(500).Find(&posts)
 	for _, post := range posts {
 		lastmod := post.UpdatedAt.Format("2006-01-02")
 		// 根据文章新旧程度调整优先级
@@ -189,7 +189,7 @@ func (h *SEOHandler) RSSFeed(c *gin.Context) {
 
 	// 查询最新20篇文章
 	var posts []models.Post
-	db.DB.Preload("User").Preload("Node").Order("created_at DESC").Limit(20).Find(&posts)
+	db.DB.Preload("User").Preload("Node").Scopes(models.PubliclyListed).Order("created_at DESC").Limit(20).Find(&posts)
 
 	// 构建RSS XML
 	rss := `<?xml version="1.0" encoding="UTF-8"?>
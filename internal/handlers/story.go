@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
@@ -14,13 +16,13 @@ import (
 	"zhulink/internal/db"
 	"zhulink/internal/middleware"
 	"zhulink/internal/models"
+	"zhulink/internal/searchquery"
 	"zhulink/internal/services"
 	"zhulink/internal/utils"
 
 	"regexp"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pgvector/pgvector-go"
 	"gorm.io/gorm"
 )
 
@@ -70,28 +72,57 @@ func fillCommentCounts(posts []models.Post) {
 	}
 }
 
-// Hacker News Ranking Algorithm: (P-1) / (T+2)^G
-// P = points of an item (and -1 is to negate submitters vote)
-// T = time since submission (in hours)
-// G = Gravity, defaults to 1.8
-func calculateRank(score int, createdAt time.Time) float64 {
-	p := float64(score)
-	t := time.Since(createdAt).Hours()
-	g := 1.8
-	return (p - 1.0) / math.Pow(t+2.0, g)
+// FlatComment 详情页展示用的评论（无层级，按楼层排列）
+type FlatComment struct {
+	models.Comment
+	ContentHTML template.HTML
+	EditBody    string // 去掉回复引用前缀后的原始内容，用于编辑表单
+	Floor       int    // 楼层号，始终按发布时间计算，不随排序变化
+	Upvotes     int
+	Downvotes   int
+	Depth       int // 楼中楼展示时的缩进层级
 }
 
-// In a real app we would compute rank in DB or background worker.
-// For this MVP, we fetch top 100 recent stories and sort them in Go, or use simple DB order.
-// Requirement: "Top/New".
-// "New" is easy: ORDER BY created_at DESC.
-// "Top" is Hacker News rank.
-// Optimization: For now, let's fetch last 200 posts and sort them in memory, or just Sort by Score for simplicity if User prefers?
-// User said: "Ranking Algorithm: 类似 Hacker News ...".
-// Let's implement a simple SQL based approximation or fetch & sort.
-// SQL approximation: score / power(extract(epoch from age(created_at))/3600 + 2, 1.8)
-// PostgreSQL: `score / power((EXTRACT(EPOCH FROM NOW() - created_at)/3600) + 2, 1.8)`
-// Note: This prevents using index effectively. But for MVP it's fine.
+// invalidateDetailCache 失效帖子详情页在所有评论排序/展示方式下的共享缓存
+func invalidateDetailCache(pid string) {
+	for _, sortMode := range commentSortModes {
+		for _, viewMode := range commentViewModes {
+			utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s:%s:%s", pid, sortMode, viewMode))
+		}
+	}
+}
+
+// listCachePrefixes 帖子列表页的缓存前缀，内容被隐藏、恢复或删除时需要一并失效
+var listCachePrefixes = []string{"story:top:", "story:rank:", "story:node:"}
+
+// invalidateListCaches 失效所有帖子列表页（各排序、各页）的缓存
+func invalidateListCaches() {
+	for _, prefix := range listCachePrefixes {
+		utils.GetCache().DeletePrefix(prefix)
+	}
+}
+
+// canViewPost 隔离中的帖子只有作者和管理员可以查看
+func canViewPost(post models.Post, user *models.User) bool {
+	if post.ModerationState != models.ModerationQuarantined {
+		return true
+	}
+	return user != nil && (user.ID == post.UserID || user.Role == "admin")
+}
+
+// editableCommentIDs 计算当前用户仍在可编辑时间内的评论 ID（随请求变化，不写入共享缓存）
+func editableCommentIDs(comments []FlatComment, user *models.User) map[uint]bool {
+	editable := make(map[uint]bool)
+	if user == nil {
+		return editable
+	}
+	for i := range comments {
+		if services.CanEditComment(user, &comments[i].Comment) == nil {
+			editable[comments[i].ID] = true
+		}
+	}
+	return editable
+}
 
 // extractFirstImage 从 Markdown 内容中提取第一张图片的 URL
 func extractFirstImage(content string) string {
@@ -125,7 +156,7 @@ func (h *StoryHandler) ListTop(c *gin.Context) {
 
 	// 查询总数
 	var total int64
-	db.DB.Model(&models.Post{}).Count(&total)
+	db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed).Count(&total)
 
 	// 计算总页数
 	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
@@ -134,7 +165,7 @@ func (h *StoryHandler) ListTop(c *gin.Context) {
 	}
 
 	var posts []models.Post
-	db.DB.Preload("User").Preload("Node").
+	db.DB.Preload("User").Preload("Node").Scopes(models.PubliclyListed).
 		Order("is_top DESC, score DESC, created_at DESC").
 		Limit(perPage).
 		Offset(offset).
@@ -166,6 +197,8 @@ func (h *StoryHandler) ListTop(c *gin.Context) {
 		"Description": "汇聚 ZhuLink 社区近期热度最高的科技资讯、深度讨论与 RSS 精选。依靠用户“竹笋”共识筛选，拒绝算法推荐，只看最有价值的内容。",
 		"Keywords":    "ZhuLink, 竹林, 技术社区, 热门文章, 高质量内容, 去算法, 技术社区, RSS聚合, Go语言, 独立开发, 深度阅读, ZhuLink",
 		"FullURL":     fullURL,
+		"RankTabs":    rankTabs(),
+		"Ranker":      utils.RankerHot,
 	}
 
 	// 写入缓存，有效期 1 分钟
@@ -188,7 +221,7 @@ func (h *StoryHandler) ListNew(c *gin.Context) {
 
 	// 查询总数
 	var total int64
-	db.DB.Model(&models.Post{}).Count(&total)
+	db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed).Count(&total)
 
 	// 计算总页数
 	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
@@ -197,7 +230,7 @@ func (h *StoryHandler) ListNew(c *gin.Context) {
 	}
 
 	var posts []models.Post
-	db.DB.Preload("User").Preload("Node").
+	db.DB.Preload("User").Preload("Node").Scopes(models.PubliclyListed).
 		Order("created_at DESC").
 		Limit(perPage).
 		Offset(offset).
@@ -242,6 +275,12 @@ func (h *StoryHandler) ListByNode(c *gin.Context) {
 		return
 	}
 
+	// 带 t 参数时展示节点内的时间窗口榜单
+	if window, ok := findTopWindow(c.Query("t")); ok {
+		h.renderTopWindow(c, window, &node)
+		return
+	}
+
 	// 分页参数
 	page := 1
 	if p := c.Query("page"); p != "" {
@@ -255,7 +294,7 @@ func (h *StoryHandler) ListByNode(c *gin.Context) {
 
 	// 查询该节点下的文章总数
 	var total int64
-	db.DB.Model(&models.Post{}).Where("node_id = ?", node.ID).Count(&total)
+	db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed).Where("node_id = ?", node.ID).Count(&total)
 
 	// 计算总页数
 	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
@@ -265,7 +304,7 @@ func (h *StoryHandler) ListByNode(c *gin.Context) {
 
 	// 查询该节点下的文章
 	var posts []models.Post
-	db.DB.Preload("User").Preload("Node").
+	db.DB.Preload("User").Preload("Node").Scopes(models.PubliclyListed).
 		Where("node_id = ?", node.ID).
 		Order("created_at DESC").
 		Limit(perPage).
@@ -304,46 +343,103 @@ func (h *StoryHandler) ListByNode(c *gin.Context) {
 		"Description": description,
 		"Keywords":    fmt.Sprintf("ZhuLink, 竹林, %s, 技术分享", node.Name),
 		"FullURL":     fullURL,
+		"TopWindows":  topWindows,
+		"Window":      "",
+		"WindowBase":  "/t/" + url.PathEscape(node.Name),
 	})
 }
 
+// Search 全文检索（标题、SEO 关键词、正文、评论），默认融合向量语义检索，并高亮命中词。
+// q 支持 searchquery 的过滤语法，侧边栏提交的非空同名参数（node、author 等）优先于 q 中的写法。
 func (h *StoryHandler) Search(c *gin.Context) {
-	query := c.Query("q")
+	query := searchquery.Parse(c.Query("q"))
+	for _, key := range searchquery.FilterKeys {
+		if value := c.Query(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+	// 语义检索开关，semantic=0 时只用关键词检索
+	semantic := c.Query("semantic") != "0"
 
-	var posts []models.Post
+	// 分页参数
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+	perPage := 20
 
-	if query != "" {
-		// 搜索标题和内容
-		searchPattern := "%" + query + "%"
-		db.DB.Preload("User").Preload("Node").
-			Where("title ILIKE ? OR content ILIKE ?", searchPattern, searchPattern).
-			Order("created_at DESC").
-			Limit(50).
-			Find(&posts)
+	var results []services.SearchResult
+	var total int64
+	semanticUsed := false
+	if !query.IsEmpty() {
+		var posts []models.Post
+		var count int64
+		var err error
+		if semantic {
+			posts, count, semanticUsed, err = services.HybridSearchPosts(query, page, perPage)
+		} else {
+			posts, count, err = services.SearchPosts(query, page, perPage)
+		}
+		if err != nil {
+			fmt.Printf("[Search] 搜索失败 (q=%q): %v\n", query.String(), err)
+		}
+		fillCommentCounts(posts)
+		results = services.HighlightSearchResults(posts, query.Keywords())
+		total = count
 	}
 
-	fillCommentCounts(posts)
+	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	// 当前检索条件的 URL 参数（不含页码），供分页链接复用
+	params := query.Values()
+	if !semantic {
+		params.Set("semantic", "0")
+	}
+
+	// 获取节点列表（用于节点过滤）
+	var nodes []models.Node
+	db.DB.Order("id ASC").Find(&nodes)
 
 	// SEO 数据
 	siteURL := os.Getenv("SITE_URL")
 	if siteURL == "" {
 		siteURL = "https://zhulink.vip"
 	}
-	fullURL := fmt.Sprintf("%s/search?q=%s", siteURL, query)
+	fullURL := fmt.Sprintf("%s/search?%s", siteURL, params.Encode())
+	if page > 1 {
+		fullURL = fmt.Sprintf("%s&page=%d", fullURL, page)
+	}
 
+	queryString := query.String()
 	description := "在 ZhuLink 竹林搜索优质内容和技术文章"
-	if query != "" {
-		description = fmt.Sprintf("在 ZhuLink 竹林搜索 '%s' 的结果", query)
+	if queryString != "" {
+		description = fmt.Sprintf("在 ZhuLink 竹林搜索 '%s' 的结果", queryString)
 	}
 
 	Render(c, http.StatusOK, "search.html", gin.H{
-		"Posts":       posts,
-		"Query":       query,
-		"Active":      "search",
-		"Title":       "搜索 - " + query,
-		"Description": description,
-		"Keywords":    fmt.Sprintf("ZhuLink, 竹林, 搜索, %s", query),
-		"FullURL":     fullURL,
+		"Results":      results,
+		"Total":        total,
+		"Query":        query.Text(),
+		"QueryString":  queryString,
+		"HasQuery":     !query.IsEmpty(),
+		"Filters":      query,
+		"Sort":         query.SortOrDefault(),
+		"Nodes":        nodes,
+		"SearchParams": template.URL(params.Encode()),
+		"Semantic":     semantic,
+		"SemanticUsed": semanticUsed,
+		"CurrentPage":  page,
+		"TotalPages":   totalPages,
+		"Active":       "search",
+		"Title":        "搜索 - " + queryString,
+		"Description":  description,
+		"Keywords":     fmt.Sprintf("ZhuLink, 竹林, 搜索, %s", queryString),
+		"FullURL":      fullURL,
 	})
 }
 
@@ -438,117 +534,92 @@ func (h *StoryHandler) Create(c *gin.Context) {
 		}
 	}()
 
-	// 异步生成 SEO 元数据和向量
-	go h.asyncGeneratePostMeta(post.ID, title, content)
+	// 异步建立全文索引
+	services.IndexPostAsync(post.ID)
+
+	// 后台生成 SEO 元数据和向量，之后匹配保存的搜索（被判定为广告而删除的帖子不会提醒）
+	enqueuePostMeta(postMetaJob{PostID: post.ID, MatchSavedSearches: true})
 
-	// 异步提交到 IndexNow
+	// 后台提交到 IndexNow
 	services.GetIndexNowService().SubmitURL(post.Pid)
 
 	c.Redirect(http.StatusFound, "/p/"+post.Pid)
 }
 
-// asyncGeneratePostMeta 异步生成 SEO 元数据和向量
-func (h *StoryHandler) asyncGeneratePostMeta(postID uint, postTitle, postContent string) {
-	llm := services.GetLLMService()
-	seoMeta, err := llm.GenerateSEOMetadata(postTitle, postContent)
-	if err != nil {
-		// SEO 生成失败不影响主流程，仅记录日志
-		fmt.Printf("[SEO] 生成 SEO 元数据失败 (postID=%d): %v\n", postID, err)
-		// 继续生成向量，不因为 SEO 失败而终止
-	}
-
-	// 检查是否被判定为广告
-	if seoMeta != nil && seoMeta.Keywords == "AD" {
-		h.handleAdPostPunishment(postID)
-		return
-	}
-
+// generatePostMeta 生成 SEO 元数据和向量，被判定为广告时隔离帖子并处罚作者（adPost 为 true）。
+// regenerateSEO 为 false 时保留已有的 SEO 元数据，只补全向量；未配置 LLM 或向量服务时跳过对应步骤。
+// 任一步骤失败时写入已成功的部分并返回错误，由任务队列重试。
+func (h *StoryHandler) generatePostMeta(ctx context.Context, post models.Post, regenerateSEO bool) (adPost bool, err error) {
+	var seoErr, vectorErr error
+	keywords, description := post.SEOKeywords, post.SEODescription
 	updateFields := map[string]interface{}{}
-	if seoMeta != nil {
-		updateFields["seo_keywords"] = seoMeta.Keywords
-		updateFields["seo_description"] = seoMeta.Description
-	}
-
-	// 生成向量文本并获取向量
-	keywords := ""
-	description := ""
-	if seoMeta != nil {
-		keywords = seoMeta.Keywords
-		description = seoMeta.Description
-	}
 
-	// 截取正文前 200 字用于向量生成
-	contentRunes := []rune(postContent)
-	shortContent := postContent
-	if len(contentRunes) > 200 {
-		shortContent = string(contentRunes[:200])
+	if llm := services.GetLLMService(); regenerateSEO && llm.Configured() {
+		seoMeta, err := llm.GenerateSEOMetadata(ctx, post.Title, post.Content)
+		if err != nil {
+			// SEO 失败仍继续生成向量
+			fmt.Printf("[SEO] 生成 SEO 元数据失败 (postID=%d): %v\n", post.ID, err)
+			seoErr = err
+		} else if seoMeta.Keywords == "AD" && post.ModerationState == models.ModerationApproved {
+			// 管理员审核恢复过的帖子不再隔离，只是没有 SEO 元数据；记录哈希避免详情页反复补全
+			fmt.Printf("[SEO] 已恢复的帖子 %d 再次被判定为广告，跳过隔离\n", post.ID)
+			updateFields["seo_prompt_version"] = seoMeta.PromptVersion
+			updateFields["meta_hash"] = postContentHash(post.Title, post.Content)
+		} else if seoMeta.Keywords == "AD" {
+			// 被判定为广告
+			return true, h.quarantineAdPost(post, seoMeta)
+		} else {
+			keywords, description = seoMeta.Keywords, seoMeta.Description
+			updateFields["seo_keywords"] = keywords
+			updateFields["seo_description"] = description
+			updateFields["seo_prompt_version"] = seoMeta.PromptVersion
+			// 记录本次生成所依据的内容，编辑时据此判断是否需要重新生成
+			updateFields["meta_hash"] = postContentHash(post.Title, post.Content)
+		}
 	}
 
-	vectorText := fmt.Sprintf("标题：%s\n关键词：%s\n摘要：%s\n正文：%s", postTitle, keywords, description, shortContent)
-	embedding, err := llm.GetEmbedding(vectorText)
-	if err != nil {
-		fmt.Printf("[Vector] 生成向量失败 (postID=%d): %v\n", postID, err)
-	} else {
-		updateFields["vector_text"] = vectorText
-		vec := pgvector.NewVector(embedding)
-		updateFields["embedding"] = &vec
+	// 生成向量文本并获取向量
+	embedding := services.GetEmbeddingService()
+	if embedding.Enabled() && (len(updateFields) > 0 || post.Embedding == nil || len(post.Embedding.Slice()) == 0) {
+		vectorText := services.PostVectorText(post.Title, keywords, description, post.Content)
+		vectorFields, err := embedding.EmbedPostFields(ctx, vectorText)
+		if err != nil {
+			fmt.Printf("[Vector] 生成向量失败 (postID=%d): %v\n", post.ID, err)
+			vectorErr = err
+		} else {
+			for k, v := range vectorFields {
+				updateFields[k] = v
+			}
+		}
 	}
 
 	if len(updateFields) > 0 {
-		var post models.Post
-		if err := db.DB.Model(&models.Post{}).Where("id = ?", postID).Updates(updateFields).Error; err != nil {
-			fmt.Printf("[Async] 更新帖子 %d 异步数据失败: %v\n", postID, err)
-			return
+		if err := db.DB.Model(&models.Post{}).Where("id = ?", post.ID).Updates(updateFields).Error; err != nil {
+			return false, fmt.Errorf("更新帖子 %d 异步数据失败: %w", post.ID, err)
 		}
-		// 获取最新的 Pid 以便清除缓存
-		if err := db.DB.Select("pid").First(&post, postID).Error; err == nil {
-			utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
+		// SEO 关键词和描述参与全文检索，需要重建索引
+		if _, ok := updateFields["seo_description"]; ok {
+			services.IndexPostAsync(post.ID)
 		}
-		fmt.Printf("[Async] 已更新帖子 %d 的 SEO 和向量数据\n", postID)
-	}
-}
-
-// handleAdPostPunishment 处理 AI 识别出的广告贴惩罚
-func (h *StoryHandler) handleAdPostPunishment(postID uint) {
-	var post models.Post
-	if err := db.DB.Preload("User").First(&post, postID).Error; err != nil {
-		return
+		invalidateDetailCache(post.Pid)
+		fmt.Printf("[Async] 已更新帖子 %d 的 SEO 和向量数据\n", post.ID)
 	}
 
-	fmt.Printf("[AL-AntiSpam] 自动拦截广告贴: postID=%d, UserID=%d, Title=%s\n", postID, post.UserID, post.Title)
-
-	// 1. 禁言用户 1 天
-	expires := time.Now().AddDate(0, 0, 1)
-	db.DB.Model(&models.User{}).Where("id = ?", post.UserID).Updates(map[string]interface{}{
-		"status":         1, // 禁言
-		"punish_expires": &expires,
-	})
+	return false, errors.Join(seoErr, vectorErr)
+}
 
-	// 2. 发送通知给用户
-	userNotification := models.Notification{
-		UserID: post.UserID,
-		Type:   models.NotificationTypeSystem,
-		Reason: "系统检测到您发布的帖子《" + html.EscapeString(post.Title) + "》包含广告信息。根据社区规则，您的账号已被自动禁言 1 天。如有误判请联系管理员。",
-	}
-	db.DB.Create(&userNotification)
-
-	// 3. 发送通知给所有管理员
-	var admins []models.User
-	db.DB.Where("role = ?", "admin").Find(&admins)
-	for _, admin := range admins {
-		adminNotification := models.Notification{
-			UserID: admin.ID,
-			Type:   models.NotificationTypeSystem,
-			Reason: fmt.Sprintf("AI 自动拦截了一条来自用户 @%s 的广告贴《%s》，已自动执行禁言 1 天处理。", post.User.Username, html.EscapeString(post.Title)),
+// quarantineAdPost 隔离 AI 识别出的广告贴：禁言作者、扣除积分，作者可申诉
+func (h *StoryHandler) quarantineAdPost(post models.Post, seoMeta *services.SEOMetadata) error {
+	if _, err := services.QuarantinePost(post.ID, seoMeta.AdReason, seoMeta.PromptVersion); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 帖子已删除
 		}
-		db.DB.Create(&adminNotification)
+		return fmt.Errorf("隔离帖子 %d 失败: %w", post.ID, err)
 	}
 
-	// 4. 删除帖子
-	db.DB.Delete(&post)
-
-	// 5. 失效缓存
-	utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
+	invalidateDetailCache(post.Pid)
+	invalidateListCaches()
+	return nil
 }
 
 func (h *StoryHandler) Detail(c *gin.Context) {
@@ -556,18 +627,28 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 
 	// 获取当前用户 ID 用于实时状态查询
 	userID := uint(0)
+	var currentUser *models.User
 	if user, exists := c.Get(middleware.CheckUserKey); exists && user != nil {
-		userID = user.(*models.User).ID
+		currentUser = user.(*models.User)
+		userID = currentUser.ID
 	}
 
-	// 共享缓存逻辑：不再区分用户
-	cacheKey := fmt.Sprintf("story:detail:shared:%s", pid)
+	// 评论排序与展示方式
+	commentSort, commentView := resolveCommentModes(c.Query("sort"), c.Query("view"), currentUser)
+
+	// 共享缓存逻辑：不再区分用户，但按评论排序/展示方式区分
+	cacheKey := fmt.Sprintf("story:detail:shared:%s:%s:%s", pid, commentSort, commentView)
 	if cachedData := utils.GetCache().Get(cacheKey); cachedData != nil {
 		if hData, ok := cachedData.(gin.H); ok {
+			if postData, ok := hData["Post"].(models.Post); ok && !canViewPost(postData, currentUser) {
+				Render(c, http.StatusNotFound, "error.html", gin.H{"Error": "文章不存在"})
+				return
+			}
+
 			// 即使是缓存，也要增加浏览量
 			if postData, ok := hData["Post"].(models.Post); ok {
 				db.DB.Model(&models.Post{}).Where("id = ?", postData.ID).UpdateColumn("views", gorm.Expr("views + 1"))
-				services.GetRankingService().ScheduleUpdate(postData.ID)
+				services.GetRankingService().ScheduleViewUpdate(postData.ID)
 			}
 
 			// 实时查询当前用户的私有状态（如是否已收藏）
@@ -582,13 +663,17 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 			}
 			hData["IsBookmarked"] = isBookmarked
 
+			// 实时计算当前用户可编辑的评论
+			cachedComments, _ := hData["Comments"].([]FlatComment)
+			hData["EditableComments"] = editableCommentIDs(cachedComments, currentUser)
+
 			Render(c, http.StatusOK, "story/detail.html", hData)
 			return
 		}
 	}
 
 	var post models.Post
-	if err := db.DB.Preload("User").Preload("Node").Where("pid = ?", pid).First(&post).Error; err != nil {
+	if err := db.DB.Preload("User").Preload("Node").Where("pid = ?", pid).First(&post).Error; err != nil || !canViewPost(post, currentUser) {
 		Render(c, http.StatusNotFound, "error.html", gin.H{"Error": "文章不存在"})
 		return
 	}
@@ -597,33 +682,31 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 	db.DB.Model(&post).UpdateColumn("views", post.Views+1)
 	post.Views++
 
-	// 如果 SEO 描述或向量为空，异步生成
-	if post.SEODescription == "" || post.Embedding == nil || len(post.Embedding.Slice()) == 0 {
-		go h.asyncGeneratePostMeta(post.ID, post.Title, post.Content)
+	// 如果 SEO 描述或向量为空，加入后台任务补全（同一帖子短时间内只入队一次）
+	if postMetaMissing(post) {
+		enqueueMissingPostMeta(post.ID)
 	}
 
 	// 异步更新帖子 Score
-	services.GetRankingService().ScheduleUpdate(post.ID)
+	services.GetRankingService().ScheduleViewUpdate(post.ID)
 
 	// Load comments
 	var comments []models.Comment
 	db.DB.Preload("User").Where("post_id = ?", post.ID).Order("created_at ASC").Find(&comments)
 
-	type FlatComment struct {
-		models.Comment
-		ContentHTML template.HTML
-		Floor       int
-	}
-
 	flatComments := make([]FlatComment, len(comments))
 	for i, com := range comments {
 		htmlContent := utils.RenderMarkdown(com.Content)
+		_, editBody := services.SplitReplyPrefix(com.Content)
 		flatComments[i] = FlatComment{
 			Comment:     com,
 			ContentHTML: htmlContent,
+			EditBody:    editBody,
 			Floor:       i + 1,
 		}
 	}
+	fillCommentVotes(flatComments)
+	flatComments = arrangeComments(flatComments, commentSort, commentView)
 
 	postContentHTML := utils.RenderMarkdown(post.Content)
 
@@ -672,23 +755,24 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 
 	var prevPost models.Post
 	hasPrev := db.DB.Select("pid, title").
-		Where("created_at < ?", post.CreatedAt).
+		Where("created_at < ? AND moderation_state <> ?", post.CreatedAt, models.ModerationQuarantined).
 		Order("created_at DESC").
 		First(&prevPost).Error == nil
 
 	var nextPost models.Post
 	hasNext := db.DB.Select("pid, title").
-		Where("created_at > ?", post.CreatedAt).
+		Where("created_at > ? AND moderation_state <> ?", post.CreatedAt, models.ModerationQuarantined).
 		Order("created_at ASC").
 		First(&nextPost).Error == nil
 
-	// 相关文章推荐 (向量相似度 > 0.7)
+	// 相关文章推荐 (向量相似度 > 0.7)，不推荐被折叠、删除或隔离的帖子
 	// pgvector 相似度公式: 1 - (embedding <=> query_embedding) > 0.7 => embedding <=> query_embedding < 0.3
 	var relatedPosts []models.Post
-	if post.Embedding != nil && len(post.Embedding.Slice()) > 0 {
-		db.DB.Model(&models.Post{}).
+	// 只与同一模型生成的向量比较，切换模型迁移期间不混用
+	if post.Embedding != nil && len(post.Embedding.Slice()) > 0 && post.EmbeddingModel != "" {
+		db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed).
 			Select("pid, title, seo_description, views, created_at, (1 - (embedding <=> ?)) as similarity", post.Embedding).
-			Where("id != ? AND (1 - (embedding <=> ?)) > 0.7", post.ID, post.Embedding).
+			Where("id != ? AND embedding_model = ? AND (1 - (embedding <=> ?)) > 0.7", post.ID, post.EmbeddingModel, post.Embedding).
 			Order("similarity DESC").
 			Limit(6).
 			Find(&relatedPosts)
@@ -712,6 +796,8 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 		"Post":          post,
 		"PostContent":   postContentHTML,
 		"Comments":      flatComments,
+		"CommentSort":   commentSort,
+		"CommentView":   commentView,
 		"Title":         post.Title,
 		"BookmarkCount": bookmarkCount,
 		"UpvoteCount":   upvoteCount,
@@ -731,6 +817,16 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 		"RelatedPosts":  relatedPosts,
 	}
 
+	// 评论数达到阈值时在评论区顶部加载讨论要点
+	if services.GetLLMService().Configured() {
+		renderData["DigestThreshold"] = services.ThreadDigestMinComments()
+	}
+
+	// 隔离中的帖子附带判定理由和申诉进度（只有作者和管理员能看到）
+	if post.ModerationState == models.ModerationQuarantined {
+		renderData["Quarantine"] = services.GetPostQuarantine(post.ID)
+	}
+
 	// 写入共享缓存，有效期延长至 5 分钟
 	utils.GetCache().Set(cacheKey, renderData, 5*time.Minute)
 
@@ -743,6 +839,7 @@ func (h *StoryHandler) Detail(c *gin.Context) {
 		}
 	}
 	renderData["IsBookmarked"] = isBookmarked
+	renderData["EditableComments"] = editableCommentIDs(flatComments, currentUser)
 
 	Render(c, http.StatusOK, "story/detail.html", renderData)
 }
@@ -795,6 +892,10 @@ func (h *StoryHandler) CreateComment(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/p/"+pid)
 		return
 	}
+	if err := services.ValidateCommentBody(content); err != nil {
+		Render(c, http.StatusBadRequest, "error.html", gin.H{"Error": err.Error()})
+		return
+	}
 
 	// 如果是回复评论，在内容开头拼接回复引用
 	var parentID *uint
@@ -826,7 +927,20 @@ func (h *StoryHandler) CreateComment(c *gin.Context) {
 	}
 
 	// 主动失效详情页缓存
-	utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
+	invalidateDetailCache(post.Pid)
+
+	// 评论参与帖子的全文检索
+	services.IndexPostAsync(post.ID)
+
+	// AI 审核评论（后台任务，不阻塞发布），审核通过后匹配保存的搜索
+	enqueueCommentModeration(comment.ID)
+
+	// 推送给正在浏览该文章的其他读者
+	go services.GetLiveHub().Publish(services.LiveEvent{
+		PostID:    post.ID,
+		Type:      services.LiveEventCommentCreated,
+		CommentID: comment.ID,
+	})
 
 	// 异步更新帖子 Score（新增评论）
 	services.GetRankingService().ScheduleUpdate(post.ID)
@@ -885,6 +999,36 @@ func (h *StoryHandler) CreateComment(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/p/"+pid)
 }
 
+// Appeal 作者对被 AI 隔离的帖子提交申诉，交由管理员审核
+func (h *StoryHandler) Appeal(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+	pid := c.Param("pid")
+
+	var post models.Post
+	if err := db.DB.Where("pid = ?", pid).First(&post).Error; err != nil || post.UserID != user.ID {
+		Render(c, http.StatusNotFound, "error.html", gin.H{"Error": "文章不存在"})
+		return
+	}
+
+	text := strings.TrimSpace(c.PostForm("appeal"))
+	if text == "" {
+		Render(c, http.StatusBadRequest, "error.html", gin.H{"Error": "请填写申诉理由"})
+		return
+	}
+
+	if err := services.SubmitAppeal(post.ID, user, text); err != nil {
+		if errors.Is(err, services.ErrQuarantineNotFound) || errors.Is(err, services.ErrAppealNotAllowed) {
+			Render(c, http.StatusBadRequest, "error.html", gin.H{"Error": err.Error()})
+			return
+		}
+		Render(c, http.StatusInternalServerError, "error.html", gin.H{"Error": "提交申诉失败"})
+		return
+	}
+
+	invalidateDetailCache(post.Pid)
+	c.Redirect(http.StatusFound, "/p/"+post.Pid)
+}
+
 // DeleteComment 软删除评论（只替换内容，保留用户名）
 func (h *StoryHandler) DeleteComment(c *gin.Context) {
 	user := c.MustGet(middleware.CheckUserKey).(*models.User)
@@ -903,14 +1047,21 @@ func (h *StoryHandler) DeleteComment(c *gin.Context) {
 	}
 
 	// 软删除：只替换内容
-	comment.Content = "该评论已删除。"
+	comment.Content = services.CommentDeletedContent
 	db.DB.Save(&comment)
 
 	// 主动失效详情页缓存
 	var post models.Post
 	if err := db.DB.First(&post, comment.PostID).Error; err == nil {
-		utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
+		invalidateDetailCache(post.Pid)
 	}
+	services.IndexPostAsync(comment.PostID)
+
+	go services.GetLiveHub().Publish(services.LiveEvent{
+		PostID:    comment.PostID,
+		Type:      services.LiveEventCommentDeleted,
+		CommentID: comment.ID,
+	})
 
 	// 异步扣除积分
 	services.AddPointsAsync(user.ID, services.PointsCommentDeleted, services.ActionCommentDeleted)
@@ -918,6 +1069,55 @@ func (h *StoryHandler) DeleteComment(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// UpdateComment 编辑评论（仅作者本人，且在按等级配置的时间窗口内）
+func (h *StoryHandler) UpdateComment(c *gin.Context) {
+	user := c.MustGet(middleware.CheckUserKey).(*models.User)
+	cid := c.Param("cid")
+
+	var comment models.Comment
+	if err := db.DB.Preload("Post").Where("cid = ?", cid).First(&comment).Error; err != nil {
+		Render(c, http.StatusNotFound, "error.html", gin.H{"Error": "评论不存在"})
+		return
+	}
+
+	// 禁言期间不允许通过编辑发布内容
+	if user.Status == 1 && (user.PunishExpires == nil || time.Now().Before(*user.PunishExpires)) {
+		Render(c, http.StatusForbidden, "error.html", gin.H{"Error": "您处于禁言状态,暂时无法编辑评论。"})
+		return
+	}
+
+	if err := services.CanEditComment(user, &comment); err != nil {
+		Render(c, http.StatusForbidden, "error.html", gin.H{"Error": err.Error()})
+		return
+	}
+
+	commentLink := fmt.Sprintf("/p/%s#comment-%d", comment.Post.Pid, comment.ID)
+
+	content := strings.TrimSpace(c.PostForm("content"))
+	if content == "" {
+		c.Redirect(http.StatusFound, commentLink)
+		return
+	}
+
+	if err := services.EditComment(&comment, user.ID, content); err != nil {
+		if errors.Is(err, services.ErrCommentReserved) {
+			Render(c, http.StatusBadRequest, "error.html", gin.H{"Error": err.Error()})
+			return
+		}
+		Render(c, http.StatusInternalServerError, "error.html", gin.H{"Error": "编辑失败"})
+		return
+	}
+
+	// 编辑已提交，失效详情页缓存并重建帖子全文索引
+	invalidateDetailCache(comment.Post.Pid)
+	services.IndexPostAsync(comment.PostID)
+
+	// 防止通过编辑绕过审核，编辑后重新审核
+	scheduleCommentModeration(comment.ID)
+
+	c.Redirect(http.StatusFound, commentLink)
+}
+
 func (h *StoryHandler) Delete(c *gin.Context) {
 	// HTMX delete
 	user := c.MustGet(middleware.CheckUserKey).(*models.User)
@@ -938,10 +1138,9 @@ func (h *StoryHandler) Delete(c *gin.Context) {
 	db.DB.Unscoped().Delete(&post)
 
 	// 主动失效详情页等相关缓存
-	utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", post.Pid))
-	// 列表页第一页也失效
-	utils.GetCache().Delete("story:top:page:1")
-	utils.GetCache().Delete("story:new:page:1")
+	invalidateDetailCache(post.Pid)
+	// 列表页也失效
+	invalidateListCaches()
 
 	// 异步扣除积分
 	services.AddPointsAsync(user.ID, services.PointsPostDeleted, services.ActionPostDeleted)
@@ -1025,6 +1224,9 @@ func (h *StoryHandler) Update(c *gin.Context) {
 		}
 	}
 
+	// 标题或正文有实质修改时需要重新审核并生成元数据
+	contentChanged := postContentHash(post.Title, post.Content) != postContentHash(title, content)
+
 	// 更新文章
 	post.Title = title
 	post.URL = url
@@ -1042,5 +1244,16 @@ func (h *StoryHandler) Update(c *gin.Context) {
 		return
 	}
 
+	// 主动失效详情页缓存
+	invalidateDetailCache(post.Pid)
+
+	// 标题和正文已变化，重建全文索引
+	services.IndexPostAsync(post.ID)
+
+	// 防抖后重新审核（广告识别）并生成 SEO 元数据和向量，完成后再次失效详情页缓存
+	if contentChanged {
+		schedulePostMetaRefresh(post.ID)
+	}
+
 	c.Redirect(http.StatusFound, "/p/"+pid)
 }
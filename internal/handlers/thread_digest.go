@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zhulink/internal/db"
+	"zhulink/internal/middleware"
+	"zhulink/internal/models"
+	"zhulink/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// threadDigestMaxPolls 讨论要点生成中时前端轮询的最多次数（每 5 秒一次）
+const threadDigestMaxPolls = 12
+
+// ThreadDigest 评论区顶部的讨论要点（HTML 片段，滚动到评论区时加载）。
+// 没有或已过时时加入后台任务生成：没有时显示生成中并轮询，过时时先展示旧版本；最近生成失败时暂不重新生成。
+func (h *StoryHandler) ThreadDigest(c *gin.Context) {
+	var post models.Post
+	if err := db.DB.Select("id, pid, user_id, moderation_state").Where("pid = ?", c.Param("pid")).First(&post).Error; err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	var currentUser *models.User
+	if user, exists := c.Get(middleware.CheckUserKey); exists && user != nil {
+		currentUser = user.(*models.User)
+	}
+	if !canViewPost(post, currentUser) || !services.GetLLMService().Configured() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var count int64
+	db.DB.Model(&models.Comment{}).Where("post_id = ?", post.ID).Count(&count)
+	if int(count) < services.ThreadDigestMinComments() {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	digest := services.GetThreadDigest(post.ID)
+	stale := digest != nil && services.ThreadDigestStale(digest, int(count))
+	if digest == nil || stale {
+		if !services.RequestThreadDigest(post.ID) {
+			// 最近生成失败，冷却期内不再生成：有旧版本时照常展示，没有时不显示
+			stale = false
+			if digest == nil {
+				c.Status(http.StatusOK)
+				return
+			}
+		}
+	}
+
+	data := gin.H{"Post": post}
+	if digest != nil {
+		data["Digest"] = digest
+		data["DigestHTML"] = services.RenderThreadDigest(digest)
+		data["Stale"] = stale
+	} else if poll, _ := strconv.Atoi(c.Query("poll")); poll < threadDigestMaxPolls {
+		data["NextPoll"] = poll + 1
+	}
+	c.HTML(http.StatusOK, "story/thread_digest.html", data)
+}
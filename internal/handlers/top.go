@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// topWindow 时间窗口榜单：只看窗口内发布的帖子，按不衰减的互动值排序
+type topWindow struct {
+	Name     string
+	Label    string
+	Duration time.Duration // 0 表示全部时间
+	CacheTTL time.Duration // 窗口越长，榜单变化越慢，缓存越久
+}
+
+var topWindows = []topWindow{
+	{Name: "day", Label: "今日", Duration: 24 * time.Hour, CacheTTL: time.Minute},
+	{Name: "week", Label: "本周", Duration: 7 * 24 * time.Hour, CacheTTL: 5 * time.Minute},
+	{Name: "month", Label: "本月", Duration: 30 * 24 * time.Hour, CacheTTL: 15 * time.Minute},
+	{Name: "year", Label: "今年", Duration: 365 * 24 * time.Hour, CacheTTL: time.Hour},
+	{Name: "all", Label: "全部", CacheTTL: time.Hour},
+}
+
+// defaultTopWindow /top 未指定 t 参数时的窗口
+const defaultTopWindow = "week"
+
+// maxTopWindowPage 时间窗口榜单最多翻到的页数，更深的页码按最后一页处理
+const maxTopWindowPage = 50
+
+// findTopWindow 按名称查找时间窗口
+func findTopWindow(name string) (topWindow, bool) {
+	for _, w := range topWindows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return topWindow{}, false
+}
+
+// ListTopWindow 时间窗口榜单 /top?t=day|week|month|year|all
+func (h *StoryHandler) ListTopWindow(c *gin.Context) {
+	window, ok := findTopWindow(c.DefaultQuery("t", defaultTopWindow))
+	if !ok {
+		window, _ = findTopWindow(defaultTopWindow)
+	}
+	h.renderTopWindow(c, window, nil)
+}
+
+// renderTopWindow 渲染全站或单个节点的时间窗口榜单，结果按窗口和页码缓存
+func (h *StoryHandler) renderTopWindow(c *gin.Context, window topWindow, node *models.Node) {
+	// 分页参数
+	page := 1
+	if p := c.Query("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = min(pageNum, maxTopWindowPage)
+		}
+	}
+
+	cacheKey := fmt.Sprintf("story:top:window:%s:page:%d", window.Name, page)
+	if node != nil {
+		cacheKey = fmt.Sprintf("story:node:%d:window:%s:page:%d", node.ID, window.Name, page)
+	}
+	if cachedData := utils.GetCache().Get(cacheKey); cachedData != nil {
+		if hData, ok := cachedData.(gin.H); ok {
+			Render(c, http.StatusOK, "story/list.html", hData)
+			return
+		}
+	}
+
+	perPage := 30
+	offset := (page - 1) * perPage
+
+	query := db.DB.Model(&models.Post{}).Scopes(models.PubliclyListed)
+	if window.Duration > 0 {
+		query = query.Where("created_at >= ?", time.Now().Add(-window.Duration))
+	}
+	if node != nil {
+		query = query.Where("node_id = ?", node.ID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	// 查询总数
+	var total int64
+	query.Count(&total)
+
+	// 计算总页数
+	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if totalPages > maxTopWindowPage {
+		totalPages = maxTopWindowPage
+	}
+
+	var posts []models.Post
+	query.Preload("User").Preload("Node").
+		Order("engagement DESC, created_at DESC").
+		Limit(perPage).
+		Offset(offset).
+		Find(&posts)
+
+	fillCommentCounts(posts)
+
+	// 获取节点列表（用于侧边栏导航）
+	var nodes []models.Node
+	db.DB.Order("id ASC").Find(&nodes)
+
+	// SEO 数据
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "https://zhulink.vip"
+	}
+	basePath := "/top"
+	title := window.Label + "最佳"
+	active := "top_window"
+	description := fmt.Sprintf("ZhuLink 社区%s互动最多的内容。", window.Label)
+	if node != nil {
+		basePath = "/t/" + url.PathEscape(node.Name)
+		title = node.Name + " · " + title
+		active = "node"
+		description = fmt.Sprintf("ZhuLink 竹林 - %s节点%s互动最多的内容。", node.Name, window.Label)
+	}
+	fullURL := fmt.Sprintf("%s%s?t=%s", siteURL, basePath, window.Name)
+	if page > 1 {
+		fullURL = fmt.Sprintf("%s&page=%d", fullURL, page)
+	}
+
+	renderData := gin.H{
+		"Posts":       posts,
+		"Nodes":       nodes,
+		"Active":      active,
+		"Title":       title,
+		"CurrentPage": page,
+		"TotalPages":  totalPages,
+		"Description": description,
+		"Keywords":    fmt.Sprintf("ZhuLink, 竹林, %s, 技术社区, 高质量内容", title),
+		"FullURL":     fullURL,
+		"TopWindows":  topWindows,
+		"Window":      window.Name,
+		"WindowBase":  basePath,
+	}
+	if node != nil {
+		renderData["Node"] = *node
+	} else {
+		renderData["RankTabs"] = rankTabs()
+		renderData["Ranker"] = "top_window"
+	}
+
+	utils.GetCache().Set(cacheKey, renderData, window.CacheTTL)
+
+	Render(c, http.StatusOK, "story/list.html", renderData)
+}
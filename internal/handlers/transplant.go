@@ -79,10 +79,11 @@ func (h *TransplantHandler) Transplant(c *gin.Context) {
 	}
 
 	// LLM 逻辑
+	summaryPromptVersion := 0 // 推荐语由 AI 生成时记录所用的提示词版本
 	if content == "" {
 		// 如果推荐语为空，调用 LLM 生成摘要
 		llm := services.GetLLMService()
-		summary, err := llm.GenerateSummary(item.Title, item.Description)
+		summary, promptVersion, err := llm.GenerateSummary(c.Request.Context(), item.Title, item.Description)
 		if err == nil {
 			if strings.Contains(summary, "CONTENT_UNSUITABLE") {
 				// 内容不适宜逻辑
@@ -96,6 +97,7 @@ func (h *TransplantHandler) Transplant(c *gin.Context) {
 				return
 			}
 			content = summary
+			summaryPromptVersion = promptVersion
 		} else {
 			// LLM 调用失败，记录日志并使用降级方案
 			log.Printf("[Transplant] LLM 调用失败 (user_id=%d, item_id=%d): %v", user.ID, itemID, err)
@@ -124,6 +126,8 @@ func (h *TransplantHandler) Transplant(c *gin.Context) {
 		Content:    content,
 		Score:      1, // 初始分，后续可触发自动点赞
 		SourceType: "rss",
+
+		SummaryPromptVersion: summaryPromptVersion,
 	}
 
 	if err := db.DB.Create(&post).Error; err != nil {
@@ -142,6 +146,12 @@ func (h *TransplantHandler) Transplant(c *gin.Context) {
 		}
 	}()
 
+	// 异步建立全文索引
+	services.IndexPostAsync(post.ID)
+
+	// 后台生成 SEO 元数据和向量，之后匹配保存的搜索
+	enqueuePostMeta(postMetaJob{PostID: post.ID, MatchSavedSearches: true})
+
 	// 4. 返回成功提示（由 hx-swap="innerHTML" 渲染结果页）
 	c.HTML(http.StatusOK, "rss/transplant_result.html", gin.H{
 		"Success": true,
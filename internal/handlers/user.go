@@ -59,11 +59,14 @@ func (h *UserHandler) Profile(c *gin.Context) {
 	var bookmarkedPosts []models.Post
 
 	if tab == "posts" {
-		// 查询用户发布的文章
-		db.DB.Preload("Node").
+		// 查询用户发布的文章（隔离中的文章只有本人可见）
+		query := db.DB.Preload("Node").
 			Preload("User").
-			Where("user_id = ?", user.ID).
-			Order("created_at DESC").
+			Where("user_id = ?", user.ID)
+		if !isOwner {
+			query = query.Where("moderation_state <> ?", models.ModerationQuarantined)
+		}
+		query.Order("created_at DESC").
 			Limit(50).
 			Find(&posts)
 		fillCommentCounts(posts)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
@@ -21,11 +22,22 @@ func NewVoteHandler() *VoteHandler {
 	return &VoteHandler{}
 }
 
-// Vote handles upvote logic
+// Vote 处理点赞：未投票则点赞，已点赞则撤回，已点踩则改为点赞
 func (h *VoteHandler) Vote(c *gin.Context) {
+	h.castVote(c, 1)
+}
+
+// Downvote 处理点踩：未投票则点踩，已点踩则撤回，已点赞则改为点踩
+func (h *VoteHandler) Downvote(c *gin.Context) {
+	h.castVote(c, -1)
+}
+
+// castVote 投票的公共处理逻辑
+// 响应主体为当前方向的票数，另一方向的票数通过 hx-swap-oob 同步更新（改票时两者都会变化）
+func (h *VoteHandler) castVote(c *gin.Context, value int) {
 	user, exists := c.Get(middleware.CheckUserKey)
 	if !exists {
-		// HTMX should handle redirect or show login modal. For now, 401.
+		// HTMX should handle redirect or show login modal.
 		c.Header("HX-Redirect", "/login")
 		c.Status(http.StatusOK)
 		return
@@ -33,264 +45,74 @@ func (h *VoteHandler) Vote(c *gin.Context) {
 	currentUser := user.(*models.User)
 
 	itemType := c.Param("type") // "post" or "comment"
-	idStr := c.Param("id")
-	id, _ := strconv.Atoi(idStr)
+	id, _ := strconv.Atoi(c.Param("id"))
 	uID := uint(id)
 
-	tx := db.DB.Begin()
-
-	query := tx.Where("user_id = ?", currentUser.ID)
-
-	if itemType == "post" {
-		query = query.Where("post_id = ?", uID)
-	} else if itemType == "comment" {
-		query = query.Where("comment_id = ?", uID)
-	} else {
-		tx.Rollback()
-		c.Status(http.StatusBadRequest)
-		return
-	}
-
-	// Check if already voted
-	var existingVote models.Vote
-	if err := query.First(&existingVote).Error; err == nil {
-		// Already voted - return current upvote count
-		tx.Rollback()
-		var upvotes int64
-		if itemType == "post" {
-			db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = 1", uID).Count(&upvotes)
-		} else {
-			db.DB.Model(&models.Vote{}).Where("comment_id = ? AND value = 1", uID).Count(&upvotes)
+	meta := services.VoteMeta{
+		IPHash: utils.FingerprintHash(c.ClientIP()),
+		UAHash: utils.FingerprintHash(c.Request.UserAgent()),
+	}
+	result, err := services.CastVote(currentUser.ID, itemType, uID, value, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidVoteItem):
+			c.Status(http.StatusBadRequest)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.Status(http.StatusNotFound)
+		default:
+			// 包括并发重复投票触发的唯一索引冲突
+			c.Status(http.StatusConflict)
 		}
-		c.String(http.StatusOK, fmt.Sprintf("%d", upvotes))
 		return
 	}
 
-	// Create vote
-	newVote := models.Vote{
-		UserID: currentUser.ID,
-		Value:  1,
-	}
-	if itemType == "post" {
-		newVote.PostID = &uID
-	} else {
-		newVote.CommentID = &uID
-	}
-
-	if err := tx.Create(&newVote).Error; err != nil {
-		tx.Rollback()
-		c.Status(http.StatusInternalServerError)
-		return
-	}
-
-	// Update score (用于排序算法，Score 仍继续更新)
-	if itemType == "post" {
-		// Increment Post Score (用于排序算法)
-		if err := tx.Model(&models.Post{}).Where("id = ?", uID).UpdateColumn("score", gorm.Expr("score + ?", 1)).Error; err != nil {
-			tx.Rollback()
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-	} else {
-		// Increment Comment Score (用于排序算法)
-		if err := tx.Model(&models.Comment{}).Where("id = ?", uID).UpdateColumn("score", gorm.Expr("score + ?", 1)).Error; err != nil {
-			tx.Rollback()
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-	}
-
-	tx.Commit()
-
-	// 主动失效详情页缓存
-	go func() {
-		var postPid string
-		if itemType == "post" {
-			var post models.Post
-			if err := db.DB.First(&post, uID).Error; err == nil {
-				postPid = post.Pid
-			}
-		} else {
-			var comment models.Comment
-			if err := db.DB.Preload("Post").First(&comment, uID).Error; err == nil {
-				postPid = comment.Post.Pid
-			}
-		}
-		if postPid != "" {
-			utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", postPid))
-		}
-	}()
+	// 主动失效详情页缓存并推送实时票数
+	go h.afterVoteChanged(itemType, uID, result)
 
 	// 异步更新帖子 Score
 	if itemType == "post" {
 		services.GetRankingService().ScheduleUpdate(uID)
 	}
 
-	// 异步给内容作者增加积分
-	go func() {
-		var authorID uint
-		if itemType == "post" {
-			var post models.Post
-			if err := db.DB.First(&post, uID).Error; err == nil {
-				authorID = post.UserID
-			}
-			if authorID != 0 && authorID != currentUser.ID {
-				services.AddPoints(authorID, services.PointsPostLiked, services.ActionPostLiked)
-			}
-		} else {
-			var comment models.Comment
-			if err := db.DB.First(&comment, uID).Error; err == nil {
-				authorID = comment.UserID
-			}
-			if authorID != 0 && authorID != currentUser.ID {
-				services.AddPoints(authorID, services.PointsCommentLiked, services.ActionCommentLiked)
-			}
-		}
-	}()
-
-	// 返回点赞数（统计 Vote 表，而非 Post.Score）
-	var upvotes int64
-	if itemType == "post" {
-		db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = 1", uID).Count(&upvotes)
-	} else {
-		db.DB.Model(&models.Vote{}).Where("comment_id = ? AND value = 1", uID).Count(&upvotes)
+	upID, downID := fmt.Sprintf("score-post-%d", uID), fmt.Sprintf("downvote-%d", uID)
+	if itemType == "comment" {
+		upID, downID = fmt.Sprintf("score-comment-%d", uID), fmt.Sprintf("downvote-comment-%d", uID)
 	}
-	c.String(http.StatusOK, fmt.Sprintf("%d", upvotes))
-}
-
-// Downvote 处理点踩逻辑
-func (h *VoteHandler) Downvote(c *gin.Context) {
-	user, exists := c.Get(middleware.CheckUserKey)
-	if !exists {
-		c.Header("HX-Redirect", "/login")
-		c.Status(http.StatusOK)
-		return
-	}
-	currentUser := user.(*models.User)
-
-	itemType := c.Param("type") // "post" or "comment"
-	idStr := c.Param("id")
-	id, _ := strconv.Atoi(idStr)
-	uID := uint(id)
-
-	tx := db.DB.Begin()
-
-	query := tx.Where("user_id = ?", currentUser.ID)
-
-	if itemType == "post" {
-		query = query.Where("post_id = ?", uID)
-	} else if itemType == "comment" {
-		query = query.Where("comment_id = ?", uID)
-	} else {
-		tx.Rollback()
-		c.Status(http.StatusBadRequest)
-		return
-	}
-
-	// 检查是否已投票
-	var existingVote models.Vote
-	if err := query.First(&existingVote).Error; err == nil {
-		// 已投票 - 返回当前踩数
-		tx.Rollback()
-		var downvotes int64
-		if itemType == "post" {
-			db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = -1", uID).Count(&downvotes)
-		} else {
-			db.DB.Model(&models.Vote{}).Where("comment_id = ? AND value = -1", uID).Count(&downvotes)
-		}
-		c.String(http.StatusOK, fmt.Sprintf("%d", downvotes))
-		return
-	}
-
-	// 创建踩票
-	newVote := models.Vote{
-		UserID: currentUser.ID,
-		Value:  -1,
-	}
-	if itemType == "post" {
-		newVote.PostID = &uID
-	} else {
-		newVote.CommentID = &uID
-	}
-
-	if err := tx.Create(&newVote).Error; err != nil {
-		tx.Rollback()
-		c.Status(http.StatusInternalServerError)
-		return
+	count, otherID, otherCount := result.Upvotes, downID, result.Downvotes
+	if value < 0 {
+		count, otherID, otherCount = result.Downvotes, upID, result.Upvotes
 	}
+	c.String(http.StatusOK, fmt.Sprintf(`%d<span id="%s" hx-swap-oob="innerHTML">%d</span>`, count, otherID, otherCount))
+}
 
-	// 更新分数 (减少)
+// afterVoteChanged 投票变化后失效详情页缓存，并向打开详情页的客户端推送最新赞/踩数
+func (h *VoteHandler) afterVoteChanged(itemType string, itemID uint, result *services.VoteResult) {
+	var postID uint
+	var postPid string
 	if itemType == "post" {
-		if err := tx.Model(&models.Post{}).Where("id = ?", uID).UpdateColumn("score", gorm.Expr("score - ?", 1)).Error; err != nil {
-			tx.Rollback()
-			c.Status(http.StatusInternalServerError)
-			return
+		var post models.Post
+		if err := db.DB.First(&post, itemID).Error; err == nil {
+			postID, postPid = post.ID, post.Pid
 		}
 	} else {
-		if err := tx.Model(&models.Comment{}).Where("id = ?", uID).UpdateColumn("score", gorm.Expr("score - ?", 1)).Error; err != nil {
-			tx.Rollback()
-			c.Status(http.StatusInternalServerError)
-			return
+		var comment models.Comment
+		if err := db.DB.Preload("Post").First(&comment, itemID).Error; err == nil {
+			postID, postPid = comment.PostID, comment.Post.Pid
 		}
 	}
-
-	tx.Commit()
-
-	// 主动失效详情页缓存
-	go func() {
-		var postPid string
-		if itemType == "post" {
-			var post models.Post
-			if err := db.DB.First(&post, uID).Error; err == nil {
-				postPid = post.Pid
-			}
-		} else {
-			var comment models.Comment
-			if err := db.DB.Preload("Post").First(&comment, uID).Error; err == nil {
-				postPid = comment.Post.Pid
-			}
-		}
-		if postPid != "" {
-			utils.GetCache().Delete(fmt.Sprintf("story:detail:shared:%s", postPid))
-		}
-	}()
-
-	// 异步更新帖子 Score
-	if itemType == "post" {
-		services.GetRankingService().ScheduleUpdate(uID)
+	if postPid == "" {
+		return
 	}
+	invalidateDetailCache(postPid)
 
-	// 异步扣除积分：内容作者-3，点踩者-1
-	go func() {
-		var authorID uint
-		if itemType == "post" {
-			var post models.Post
-			if err := db.DB.First(&post, uID).Error; err == nil {
-				authorID = post.UserID
-			}
-			if authorID != 0 && authorID != currentUser.ID {
-				services.AddPoints(authorID, services.PointsPostDownvoted, services.ActionPostDownvoted)
-			}
-		} else {
-			var comment models.Comment
-			if err := db.DB.First(&comment, uID).Error; err == nil {
-				authorID = comment.UserID
-			}
-			if authorID != 0 && authorID != currentUser.ID {
-				services.AddPoints(authorID, services.PointsCommentDownvoted, services.ActionCommentDownvoted)
-			}
-		}
-		// 点踩者自己扣分
-		services.AddPoints(currentUser.ID, services.PointsDownvoteOther, services.ActionDownvoteOther)
-	}()
-
-	var downvotes int64
-	if itemType == "post" {
-		db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = -1", uID).Count(&downvotes)
-	} else {
-		db.DB.Model(&models.Vote{}).Where("comment_id = ? AND value = -1", uID).Count(&downvotes)
-	}
-	c.String(http.StatusOK, fmt.Sprintf("%d", downvotes))
+	services.GetLiveHub().Publish(services.LiveEvent{
+		PostID:    postID,
+		Type:      services.LiveEventVote,
+		ItemType:  itemType,
+		ItemID:    itemID,
+		Upvotes:   result.Upvotes,
+		Downvotes: result.Downvotes,
+	})
 }
 
 // Report 处理举报逻辑
@@ -323,19 +145,32 @@ func (h *VoteHandler) Report(c *gin.Context) {
 		}
 	}
 
-	report := models.Report{
-		UserID:   currentUser.ID,
-		ItemType: itemType,
-		ItemID:   uID,
-		ItemPid:  itemPid,
-		Reason:   reason,
+	if itemPid == "" {
+		c.String(http.StatusNotFound, "内容不存在")
+		return
 	}
 
-	if err := db.DB.Create(&report).Error; err != nil {
-		c.Status(http.StatusInternalServerError)
+	result, err := services.SubmitReport(currentUser, itemType, uID, itemPid, reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAlreadyReported):
+			c.String(http.StatusConflict, "您已举报过该内容")
+		case errors.Is(err, services.ErrInvalidVoteItem):
+			c.Status(http.StatusBadRequest)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.String(http.StatusNotFound, "内容不存在")
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
 		return
 	}
 
+	// 内容被自动折叠后，详情页和列表页需要立即反映
+	if result.AutoHidden {
+		invalidateDetailCache(itemPid)
+		invalidateListCaches()
+	}
+
 	// 异步向所有管理员发送举报通知
 	go func() {
 		// 查询所有管理员用户
@@ -363,6 +198,9 @@ func (h *VoteHandler) Report(c *gin.Context) {
 		// 为每个管理员创建通知
 		safeContentDesc := html.EscapeString(contentDesc)
 		safeReason := html.EscapeString(reason)
+		if result.AutoHidden {
+			safeReason += fmt.Sprintf("（举报分 %.1f 已达到阈值，内容已自动折叠，等待审核）", result.FlagScore)
+		}
 		for _, admin := range admins {
 			notification := models.Notification{
 				UserID:  admin.ID,
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"zhulink/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateWindow 固定窗口计数
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// RateLimit 按用户（未登录时按 IP）限制单位时间内的请求次数
+// 计数保存在进程内存中，每个路由使用独立的计数器
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	windows := make(map[string]*rateWindow)
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if user, exists := c.Get(CheckUserKey); exists {
+			key = fmt.Sprintf("user:%d", user.(*models.User).ID)
+		}
+
+		now := time.Now()
+		mu.Lock()
+		// 定期清理过期窗口，避免 map 无限增长
+		if now.Sub(lastSweep) > window {
+			for k, w := range windows {
+				if now.After(w.resetAt) {
+					delete(windows, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		w, ok := windows[key]
+		if !ok || now.After(w.resetAt) {
+			w = &rateWindow{resetAt: now.Add(window)}
+			windows[key] = w
+		}
+		w.count++
+		exceeded := w.count > limit
+		mu.Unlock()
+
+		if exceeded {
+			c.String(http.StatusTooManyRequests, "操作过于频繁，请稍后再试")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// AI 评论审核的违规类别
+const (
+	CommentCategoryNone     = "none"      // 正常
+	CommentCategorySpam     = "spam"      // 垃圾信息、广告引流
+	CommentCategoryAbuse    = "abuse"     // 辱骂、人身攻击、仇恨言论
+	CommentCategoryOffTopic = "off_topic" // 与帖子无关、灌水
+)
+
+// AI 审核对评论采取的动作
+const (
+	CommentModerationReview = "review" // 置信度较低：进入管理员审核队列，评论照常显示
+	CommentModerationHide   = "hide"   // 置信度高：评论折叠，等待管理员审核
+)
+
+// 审核记录状态
+const (
+	CommentModerationPending  = "pending"  // 待管理员处理
+	CommentModerationApproved = "approved" // 管理员判定正常
+	CommentModerationUpheld   = "upheld"   // 管理员确认违规
+)
+
+// CommentModeration AI 对一条评论的审核结果，只记录判定为违规且达到审核阈值的评论
+type CommentModeration struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	CommentID     uint       `gorm:"not null;uniqueIndex" json:"comment_id"`
+	Comment       Comment    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"comment"`
+	PostID        uint       `gorm:"not null;index" json:"post_id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`                 // 评论作者
+	Category      string     `gorm:"size:20;not null" json:"category"`              // 违规类别: spam, abuse, off_topic
+	Confidence    float64    `gorm:"not null" json:"confidence"`                    // 模型给出的置信度 0-1
+	Reason        string     `gorm:"size:500" json:"reason"`                        // 模型给出的理由
+	Action        string     `gorm:"size:20;not null" json:"action"`                // 采取的动作: review, hide
+	AuthorLevel   string     `gorm:"size:20" json:"author_level"`                   // 审核时作者的等级
+	PromptVersion int        `gorm:"default:0" json:"prompt_version"`               // 所用的 comment_moderation 提示词版本
+	Status        string     `gorm:"size:20;default:'pending';index" json:"status"` // 处理状态: pending, approved, upheld
+	ResolvedAt    *time.Time `json:"resolved_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
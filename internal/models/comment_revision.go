@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// CommentRevision 评论修订记录 - 每次编辑前保存旧内容，仅管理员可见
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"not null;index" json:"comment_id"`
+	Comment   Comment   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"comment"`
+	EditorID  uint      `gorm:"not null;index" json:"editor_id"`
+	Editor    User      `gorm:"foreignKey:EditorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"editor"`
+	Content   string    `gorm:"type:text;not null" json:"content"` // 编辑前的内容
+	CreatedAt time.Time `json:"created_at"`
+}
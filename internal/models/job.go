@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// 后台任务状态
+const (
+	JobStatusPending   = "pending"   // 等待执行（包括失败后等待重试）
+	JobStatusRunning   = "running"   // 执行中
+	JobStatusSucceeded = "succeeded" // 已完成
+	JobStatusDead      = "dead"      // 重试次数用尽，等待管理员处理
+)
+
+// Job 持久化的后台任务（生成 SEO 元数据、提交 IndexNow、发送邮件等），重启后继续执行
+// 同一 DedupeKey 最多只有一个等待中的任务，重复入队会被合并
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"size:50;not null;index" json:"type"`
+	DedupeKey   string     `gorm:"size:200;default:'';uniqueIndex:idx_jobs_dedupe_pending,where:status = 'pending' AND dedupe_key <> ''" json:"dedupe_key"`
+	Payload     string     `gorm:"type:text" json:"payload"` // JSON 参数
+	Status      string     `gorm:"size:20;not null;default:'pending';index:idx_jobs_status_run_at,priority:1" json:"status"`
+	RunAt       time.Time  `gorm:"not null;index:idx_jobs_status_run_at,priority:2" json:"run_at"` // 最早执行时间（延迟执行、重试退避）
+	Attempts    int        `gorm:"default:0" json:"attempts"`                                      // 已执行次数
+	MaxAttempts int        `gorm:"default:5" json:"max_attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error"`
+	LockedAt    *time.Time `json:"locked_at"` // 开始执行的时间，超时未完成视为进程崩溃，重新放回队列
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// 帖子/评论的审核状态
+// visible --(举报权重达到阈值 / AI 审核高置信度违规)--> hidden --(管理员恢复)--> approved
+//
+//	\--(管理员确认违规)--> removed
+//
+// 帖子被 AI 判定为广告时进入 quarantined，作者申诉通过后转为 approved（见 PostQuarantine）
+const (
+	ModerationVisible  = "visible"  // 正常显示
+	ModerationHidden   = "hidden"   // 被社区举报或 AI 审核自动折叠，等待审核
+	ModerationApproved = "approved" // 管理员审核后恢复，不再自动折叠
+	ModerationRemoved  = "removed"  // 管理员确认违规
+
+	ModerationQuarantined = "quarantined" // 被 AI 判定为广告而隔离，仅作者和管理员可见，可申诉
+)
+
+// unlistedStates 不出现在公开列表中的审核状态（折叠、已移除和隔离中）
+var unlistedStates = []string{ModerationHidden, ModerationRemoved, ModerationQuarantined}
+
+// PubliclyListed 只保留可以出现在公开列表中的内容（排除折叠、已移除和隔离中的内容）
+func PubliclyListed(db *gorm.DB) *gorm.DB {
+	return db.Where("moderation_state NOT IN ?", unlistedStates)
+}
+
+// IsPubliclyListed 审核状态是否允许出现在公开列表中（与 PubliclyListed 一致，用于已加载的内容）
+func IsPubliclyListed(state string) bool {
+	for _, s := range unlistedStates {
+		if state == s {
+			return false
+		}
+	}
+	return true
+}
@@ -10,7 +10,8 @@ const (
 	NotificationTypeCommentPost  NotificationType = "comment_post"
 	NotificationTypeReplyComment NotificationType = "reply_comment"
 	NotificationTypeSystem       NotificationType = "system"
-	NotificationTypeReport       NotificationType = "report" // 举报通知
+	NotificationTypeReport       NotificationType = "report"       // 举报通知
+	NotificationTypeSavedSearch  NotificationType = "saved_search" // 保存的搜索有新匹配
 )
 
 type Notification struct {
@@ -7,25 +7,41 @@ import (
 )
 
 type Post struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Pid            string    `gorm:"uniqueIndex;size:20;not null" json:"pid"`
-	UserID         uint      `gorm:"not null;index" json:"user_id"`
-	User           User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user"`
-	NodeID         uint      `gorm:"not null;index;default:1" json:"node_id"`
-	Node           Node      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"node"`
-	Title          string    `gorm:"not null" json:"title"`
-	URL            string    `json:"url"` // Optional
-	Content        string    `gorm:"type:text" json:"content"`
-	Score          int       `gorm:"default:0" json:"score"`
-	Views          int       `gorm:"default:0" json:"views"`           // 浏览/点击量
-	SourceType     string    `json:"source_type"`                      // e.g., "rss"
-	IsTop          bool      `gorm:"default:false" json:"is_top"`      // 是否置顶
-	SEOKeywords    string          `gorm:"type:text" json:"seo_keywords"`    // AI 生成的 SEO 关键词
-	SEODescription string          `gorm:"type:text" json:"seo_description"` // AI 生成的 SEO 页面描述
-	VectorText     string          `gorm:"type:text" json:"-"`               // 用于生成向量的拼接文本
-	Embedding      *pgvector.Vector `gorm:"type:vector(768)" json:"-"`         // 向量数据 (nomic-embed-text 为 768 维)
-	CreatedAt      time.Time       `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                 uint             `gorm:"primaryKey" json:"id"`
+	Pid                string           `gorm:"uniqueIndex;size:20;not null" json:"pid"`
+	UserID             uint             `gorm:"not null;index" json:"user_id"`
+	User               User             `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user"`
+	NodeID             uint             `gorm:"not null;index;default:1" json:"node_id"`
+	Node               Node             `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"node"`
+	Title              string           `gorm:"not null" json:"title"`
+	URL                string           `json:"url"` // Optional
+	Content            string           `gorm:"type:text" json:"content"`
+	Score              int              `gorm:"default:0" json:"score"`                                  // 热门分数 (hot)
+	RisingScore        float64          `gorm:"default:0;index" json:"rising_score"`                     // 飙升分数 (rising)
+	ControversialScore float64          `gorm:"default:0;index" json:"controversial_score"`              // 争议分数 (controversial)
+	BestScore          float64          `gorm:"default:0;index" json:"best_score"`                       // 最佳分数 (best)，不随时间衰减
+	Engagement         float64          `gorm:"default:0;index" json:"engagement"`                       // 不衰减的加权互动值，时间窗口榜单 (top) 按此排序
+	Views              int              `gorm:"default:0" json:"views"`                                  // 浏览/点击量
+	SourceType         string           `json:"source_type"`                                             // e.g., "rss"
+	IsTop              bool             `gorm:"default:false" json:"is_top"`                             // 是否置顶
+	ModerationState    string           `gorm:"size:20;default:'visible';index" json:"moderation_state"` // 审核状态: visible, hidden, approved, removed, quarantined
+	FlagScore          float64          `gorm:"default:0" json:"flag_score"`                             // 待处理举报的信任权重之和
+	SEOKeywords        string           `gorm:"type:text" json:"seo_keywords"`                           // AI 生成的 SEO 关键词
+	SEODescription     string           `gorm:"type:text" json:"seo_description"`                        // AI 生成的 SEO 页面描述
+	VectorText         string           `gorm:"type:text" json:"-"`                                      // 用于生成向量的拼接文本
+	Embedding          *pgvector.Vector `gorm:"type:vector" json:"-"`                                    // 向量数据，维度由 EMBEDDING_DIMENSIONS 决定，启动时校验
+	EmbeddingModel     string           `gorm:"size:200;index" json:"-"`                                 // 生成向量的提供方和模型，如 ollama:nomic-embed-text
+	EmbeddingDim       int              `gorm:"default:0" json:"-"`                                      // 生成时的向量维度
+	MetaHash           string           `gorm:"size:64" json:"-"`                                        // 生成 SEO 元数据和向量时标题+正文的哈希，编辑后据此判断是否需要重新生成
+	SEOPromptVersion   int              `gorm:"default:0" json:"-"`                                      // 生成 SEO 元数据所用的 seo 提示词版本，0 表示未记录
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+
+	// RSS 转载时 AI 生成推荐语所用的 summary 提示词版本，0 表示推荐语非 AI 生成
+	SummaryPromptVersion int `gorm:"default:0" json:"-"`
+
+	// 全文检索词元，由 services.IndexPost 维护，常规查询不读写
+	SearchVector string `gorm:"type:tsvector;index:idx_posts_search_vector,type:gin;->:false;<-:false" json:"-"`
 
 	// 非数据库字段，用于查询时填充
 	CommentCount int `gorm:"-" json:"comment_count"`
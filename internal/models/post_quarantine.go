@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// 隔离记录状态
+// quarantined --(作者申诉)--> appealed --(管理员通过)--> restored
+//
+//	\--(管理员驳回)--> rejected
+const (
+	QuarantineActive   = "quarantined" // 已隔离，作者尚未申诉
+	QuarantineAppealed = "appealed"    // 作者已申诉，等待管理员处理
+	QuarantineRestored = "restored"    // 申诉通过，帖子已恢复
+	QuarantineRejected = "rejected"    // 申诉驳回，帖子保持隔离
+)
+
+// PostQuarantine 被 AI 判定为广告而隔离的帖子，记录判定理由、对作者的处罚和申诉进度
+type PostQuarantine struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	PostID        uint       `gorm:"not null;uniqueIndex" json:"post_id"`
+	Post          Post       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"post"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"` // 帖子作者
+	User          User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user"`
+	Reason        string     `gorm:"size:500" json:"reason"`                            // 模型给出的判定理由
+	PromptVersion int        `gorm:"default:0" json:"prompt_version"`                   // 所用的 seo 提示词版本
+	PenaltyPoints int        `gorm:"default:0" json:"penalty_points"`                   // 扣除的积分，申诉通过时退还
+	MutedUntil    *time.Time `json:"muted_until"`                                       // 本次自动禁言的截止时间，为空表示未禁言（作者已被封禁或禁言更久）
+	Status        string     `gorm:"size:20;default:'quarantined';index" json:"status"` // 状态: quarantined, appealed, restored, rejected
+	AppealText    string     `gorm:"size:1000" json:"appeal_text"`                      // 申诉理由
+	AppealedAt    *time.Time `json:"appealed_at"`
+	ResolvedByID  *uint      `json:"resolved_by_id"` // 处理申诉的管理员
+	ResolvedAt    *time.Time `json:"resolved_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PromptTemplate LLM 提示词模板的一个版本。每次编辑新建一个版本，同名模板同时只有一个启用的版本；
+// 生成的内容记录所用的版本号（如 Post.SEOPromptVersion），便于追溯和回滚
+type PromptTemplate struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;not null;uniqueIndex:idx_prompt_name_version" json:"name"` // 模板名称，如 seo、summary
+	Version     int       `gorm:"not null;uniqueIndex:idx_prompt_name_version" json:"version"`      // 同名模板内递增
+	System      string    `gorm:"type:text" json:"system"`                                          // 系统提示词（text/template）
+	User        string    `gorm:"type:text" json:"user"`                                            // 用户消息（text/template），引用 {{.Title}} 等变量
+	Note        string    `gorm:"size:200" json:"note"`                                             // 修改说明
+	Active      bool      `gorm:"default:false;index" json:"active"`
+	CreatedByID uint      `gorm:"default:0" json:"created_by_id"` // 0 表示内置默认版本
+	CreatedAt   time.Time `json:"created_at"`
+}
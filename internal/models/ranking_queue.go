@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// RankingQueueItem 等待重新计算排名分数的帖子（持久化的排名更新队列，重启后继续处理）
+// 每个帖子最多一行：EnqueuedAt 为首次变脏的时间（用于计算延迟），DirtyAt 为最近一次变脏的时间
+type RankingQueueItem struct {
+	PostID     uint      `gorm:"primaryKey;autoIncrement:false" json:"post_id"`
+	EnqueuedAt time.Time `gorm:"not null;index" json:"enqueued_at"`
+	DirtyAt    time.Time `gorm:"not null" json:"dirty_at"`
+}
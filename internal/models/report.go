@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// 举报处理状态
+const (
+	ReportStatusPending  = "pending"  // 待处理
+	ReportStatusUpheld   = "upheld"   // 举报成立
+	ReportStatusRejected = "rejected" // 举报不成立
+)
+
 type Report struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	UserID    uint      `gorm:"not null;index" json:"user_id"` // Reporter
@@ -12,5 +19,7 @@ type Report struct {
 	ItemID    uint      `gorm:"not null;index" json:"item_id"`
 	ItemPid   string    `gorm:"size:8" json:"item_pid"`
 	Reason    string    `gorm:"size:200;not null" json:"reason"`
+	Weight    float64   `gorm:"default:1" json:"weight"`                       // 举报者的信任权重（举报时按等级和账号年龄计算）
+	Status    string    `gorm:"size:20;default:'pending';index" json:"status"` // 处理状态: pending, upheld, rejected
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch 用户保存的搜索（关键词监控），有新帖或新评论匹配时发送提醒
+type SavedSearch struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	User          User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Query         string     `gorm:"type:text;not null" json:"query"`            // searchquery 语法的完整查询（文本 + 过滤器）
+	Node          string     `gorm:"size:100;not null;default:''" json:"node"`   // 以下为查询中的过滤器，匹配新内容前在 SQL 中预筛，空表示不限
+	Author        string     `gorm:"size:100;not null;default:''" json:"author"` // 小写用户名
+	Site          string     `gorm:"size:255;not null;default:''" json:"site"`   // 域名，含子域名
+	Type          string     `gorm:"size:10;not null;default:''" json:"type"`    // ask 或 link
+	EmailDigest   bool       `gorm:"default:false" json:"email_digest"`          // 是否接收每日邮件摘要
+	MatchCount    int        `gorm:"default:0" json:"match_count"`               // 累计匹配次数
+	LastMatchedAt *time.Time `json:"last_matched_at"`                            // 最近一次匹配时间
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// SavedSearchMatch 保存的搜索的一次匹配，用于去重和每日邮件摘要
+type SavedSearchMatch struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	SavedSearchID uint        `gorm:"not null;uniqueIndex:idx_saved_search_match" json:"saved_search_id"`
+	SavedSearch   SavedSearch `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	UserID        uint        `gorm:"not null;index" json:"user_id"`
+	PostID        uint        `gorm:"not null;uniqueIndex:idx_saved_search_match" json:"post_id"`
+	Post          Post        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	CommentID     uint        `gorm:"not null;default:0;uniqueIndex:idx_saved_search_match" json:"comment_id"` // 0 表示匹配的是帖子本身
+	DigestPending bool        `gorm:"default:false;index" json:"digest_pending"`                               // 等待写入每日邮件摘要
+	CreatedAt     time.Time   `json:"created_at"`
+}
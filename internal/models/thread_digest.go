@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// ThreadDigest 帖子评论区的 AI 讨论要点，评论数明显增长后重新生成
+type ThreadDigest struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	PostID        uint      `gorm:"not null;uniqueIndex" json:"post_id"`
+	Post          Post      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"post"`
+	Content       string    `gorm:"type:text;not null" json:"content"` // 模型输出的 Markdown，以 #楼层 引用评论
+	CommentCount  int       `gorm:"not null" json:"comment_count"`     // 生成时的评论数
+	PromptVersion int       `gorm:"default:0" json:"prompt_version"`   // 所用的 thread_digest 提示词版本
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
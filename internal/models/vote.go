@@ -4,15 +4,17 @@ import (
 	"time"
 )
 
+// Vote 用户对帖子或评论的投票，每个用户对同一内容最多一票
+// PostgreSQL 唯一索引中 NULL 互不相等，因此 (user_id, post_id) 与 (user_id, comment_id)
+// 两个唯一索引可以分别约束帖子票和评论票，防止并发请求重复投票
 type Vote struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	PostID    *uint     `gorm:"index" json:"post_id"`
-	CommentID *uint     `gorm:"index" json:"comment_id"`
-	Value     int       `gorm:"not null" json:"value"` // 1 or -1
-	CreatedAt time.Time `json:"created_at"`
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index;uniqueIndex:idx_votes_user_post;uniqueIndex:idx_votes_user_comment" json:"user_id"`
+	PostID      *uint     `gorm:"index;uniqueIndex:idx_votes_user_post" json:"post_id"`
+	CommentID   *uint     `gorm:"index;uniqueIndex:idx_votes_user_comment" json:"comment_id"`
+	Value       int       `gorm:"not null" json:"value"`        // 1 or -1
+	IPHash      string    `gorm:"size:64;index" json:"-"`       // 投票时的 IP 指纹（哈希）
+	UAHash      string    `gorm:"size:64" json:"-"`             // 投票时的 User-Agent 指纹（哈希）
+	Neutralized bool      `gorm:"default:false;index" json:"-"` // 被判定为刷票，不参与排名计算
+	CreatedAt   time.Time `json:"created_at"`
 }
-
-// Custom hook or unique index enforcement can be done via GORM tags or DB approach.
-// To ensure one vote per item per user:
-// gorm:"uniqueIndex:idx_post_vote;uniqueIndex:idx_comment_vote" is tricky with nulls in some DBs, but PG handles (user_id, post_id) unique where post_id is not null.
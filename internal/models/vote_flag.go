@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// 刷票嫌疑类型
+const (
+	VoteFlagReciprocal        = "reciprocal"         // 互相点赞形成的投票圈
+	VoteFlagSharedFingerprint = "shared_fingerprint" // 多个账号共用 IP/UA 指纹投票
+	VoteFlagNewAccount        = "new_account"        // 注册后立即投票
+)
+
+// 嫌疑处理状态
+const (
+	VoteFlagStatusPending     = "pending"     // 待审核
+	VoteFlagStatusDismissed   = "dismissed"   // 已忽略
+	VoteFlagStatusNeutralized = "neutralized" // 相关投票已从排名计算中剔除
+)
+
+// VoteFlag 后台分析器标记的可疑投票账号，每个账号每种嫌疑类型一条记录
+type VoteFlag struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_vote_flag_user_reason" json:"user_id"`
+	User           User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user"`
+	Reason         string    `gorm:"size:30;not null;uniqueIndex:idx_vote_flag_user_reason" json:"reason"`
+	Detail         string    `gorm:"size:500" json:"detail"`
+	RelatedUserIDs string    `gorm:"size:500" json:"related_user_ids"` // 关联账号 ID，逗号分隔
+	Evidence       int       `gorm:"default:0" json:"evidence"`        // 涉及的可疑票数
+	Status         string    `gorm:"size:20;default:'pending';index" json:"status"`
+	DetectedAt     time.Time `json:"detected_at"` // 最近一次被检测到的时间
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -2,8 +2,10 @@ package router
 
 import (
 	"net/http"
+	"time"
 	"zhulink/internal/handlers"
 	"zhulink/internal/middleware"
+	"zhulink/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +24,7 @@ func RegisterRoutes(r *gin.Engine) {
 	adminHandler := handlers.NewAdminHandler()
 	seoHandler := handlers.NewSEOHandler()
 	imageHandler := handlers.NewImageHandler()
+	savedSearchHandler := handlers.NewSavedSearchHandler()
 
 	// 404 Handler
 	r.NoRoute(func(c *gin.Context) {
@@ -37,15 +40,23 @@ func RegisterRoutes(r *gin.Engine) {
 	r.GET("/:key.txt", seoHandler.IndexNowKeyFile)   // IndexNow 验证文件 (格式: {apiKey}.txt)
 
 	// 公共路由 (Public Routes)
-	r.GET("/", storyHandler.ListTop)               // 首页 - 热门文章
-	r.GET("/new", storyHandler.ListNew)            // 最新文章
-	r.GET("/search", storyHandler.Search)          // 搜索页面
-	r.GET("/p/:pid", storyHandler.Detail)          // 文章详情页
-	r.GET("/t/:name", storyHandler.ListByNode)     // 节点下的文章列表
-	r.GET("/nodes", nodeHandler.ListNodes)         // 所有节点列表
-	r.GET("/u/:id", userHandler.Profile)           // 用户主页
-	r.GET("/rss/popular", rssHandler.PopularFeeds) // 热门订阅（公开）
-	r.GET("/img/:id", imageHandler.Proxy)          // Imgur 图片反代（公开，带防盗链）
+	r.GET("/", storyHandler.ListTop)                                            // 首页 - 热门文章
+	r.GET("/new", storyHandler.ListNew)                                         // 最新文章
+	r.GET("/hot", storyHandler.ListRanked(utils.RankerHot))                     // 热门（跳转首页）
+	r.GET("/rising", storyHandler.ListRanked(utils.RankerRising))               // 飙升
+	r.GET("/controversial", storyHandler.ListRanked(utils.RankerControversial)) // 争议
+	r.GET("/best", storyHandler.ListRanked(utils.RankerBest))                   // 最佳（不随时间衰减）
+	r.GET("/top", storyHandler.ListTopWindow)                                   // 时间窗口榜单 (?t=day|week|month|year|all)
+	r.GET("/search", storyHandler.Search)                                       // 搜索页面
+	r.GET("/p/:pid", storyHandler.Detail)                                       // 文章详情页
+	r.GET("/p/:pid/live", storyHandler.Live)                                    // 文章详情页实时推送 (SSE)
+	r.GET("/p/:pid/comments/:id", storyHandler.CommentFragment)                 // 单条评论片段（实时插入新评论）
+	r.GET("/p/:pid/digest", storyHandler.ThreadDigest)                          // 评论区讨论要点片段
+	r.GET("/t/:name", storyHandler.ListByNode)                                  // 节点下的文章列表
+	r.GET("/nodes", nodeHandler.ListNodes)                                      // 所有节点列表
+	r.GET("/u/:id", userHandler.Profile)                                        // 用户主页
+	r.GET("/rss/popular", rssHandler.PopularFeeds)                              // 热门订阅（公开）
+	r.GET("/img/:id", imageHandler.Proxy)                                       // Imgur 图片反代（公开，带防盗链）
 
 	r.GET("/signup", authHandler.ShowRegister)   // 注册页面
 	r.POST("/signup", authHandler.Register)      // 提交注册
@@ -78,15 +89,22 @@ func RegisterRoutes(r *gin.Engine) {
 		authorized.POST("/bookmark/:id", bookmarkHandler.Toggle)       // 收藏/取消收藏
 		authorized.GET("/p/:pid/edit", storyHandler.ShowEdit)          // 编辑文章页面
 		authorized.POST("/p/:pid/edit", storyHandler.Update)           // 提交文章更新
+		authorized.POST("/p/:pid/appeal", storyHandler.Appeal)         // 对被隔离的文章提交申诉
 
-		authorized.DELETE("/p/:pid", storyHandler.Delete)              // 删除文章
-		authorized.DELETE("/comment/:cid", storyHandler.DeleteComment) // 删除评论
+		authorized.DELETE("/p/:pid", storyHandler.Delete)                 // 删除文章
+		authorized.DELETE("/comment/:cid", storyHandler.DeleteComment)    // 删除评论
+		authorized.POST("/comment/:cid/edit", storyHandler.UpdateComment) // 编辑评论
+
+		authorized.POST("/p/:pid/comment-modes", storyHandler.SaveCommentModes) // 保存评论排序与展示偏好
 
 		authorized.POST("/notifications/:id/read", notificationHandler.Read)    // 标记单条通知为已读
 		authorized.DELETE("/notifications/:id", notificationHandler.Delete)     // 删除单条通知
 		authorized.POST("/notifications/read-all", notificationHandler.ReadAll) // 全部通知标记为已读
 
-		authorized.POST("/api/upload", imageHandler.Upload) // 图片上传
+		authorized.POST("/search/save", savedSearchHandler.Create) // 保存当前搜索条件
+
+		authorized.POST("/api/upload", imageHandler.Upload)                                          // 图片上传
+		authorized.POST("/api/preview", middleware.RateLimit(30, time.Minute), storyHandler.Preview) // Markdown 预览
 	}
 
 	// 仪表盘路由 (Dashboard Routes)
@@ -100,6 +118,11 @@ func RegisterRoutes(r *gin.Engine) {
 		dashboard.POST("/settings", userHandler.UpdateSettings)   // 提交用户设置更新
 		dashboard.POST("/checkin", userHandler.CheckIn)           // 每日签到
 
+		// 保存的搜索
+		dashboard.GET("/searches", savedSearchHandler.List)                     // 保存的搜索列表
+		dashboard.POST("/searches/:id/delete", savedSearchHandler.Delete)       // 删除保存的搜索
+		dashboard.POST("/searches/:id/digest", savedSearchHandler.ToggleDigest) // 开关每日邮件摘要
+
 		// Google 账号绑定路由
 		dashboard.GET("/settings/bind-google", authHandler.BindGoogle)                  // 绑定 Google 账号
 		dashboard.GET("/settings/bind-google/callback", authHandler.GoogleBindCallback) // Google 绑定回调
@@ -113,6 +136,7 @@ func RegisterRoutes(r *gin.Engine) {
 		rss.GET("", rssHandler.Index)                          // RSS 阅读器主页
 		rss.GET("/feeds", rssHandler.GetFeeds)                 // 获取订阅源列表
 		rss.GET("/items", rssHandler.GetItems)                 // 获取文章项列表
+		rss.GET("/search", rssHandler.SearchItems)             // 在订阅中搜索文章
 		rss.GET("/read/:id", rssHandler.ReadItem)              // 获取单篇文章内容
 		rss.POST("/subscribe", rssHandler.Subscribe)           // 订阅新的 RSS 源
 		rss.DELETE("/unsubscribe/:id", rssHandler.Unsubscribe) // 取消订阅
@@ -130,13 +154,43 @@ func RegisterRoutes(r *gin.Engine) {
 	admin := r.Group("/admin")
 	admin.Use(middleware.AuthRequired())
 	{
-		admin.POST("/post/:pid/top", adminHandler.ToggleTop)           // 置顶
-		admin.POST("/post/:pid/move", adminHandler.MoveNode)           // 移动节点
-		admin.POST("/user/:id/punish", adminHandler.PunishUser)        // 惩罚用户
-		admin.DELETE("/post/:pid", adminHandler.AdminDeletePost)       // 管理员删除文章
-		admin.DELETE("/comment/:cid", adminHandler.AdminDeleteComment) // 管理员删除评论
-		admin.GET("/reports", adminHandler.ListReports)                // 举报列表
-		admin.DELETE("/reports/:id", adminHandler.HandleReport)        // 处理举报
-		admin.GET("/users", adminHandler.ListUsers)                    // 用户管理
+		admin.POST("/post/:pid/top", adminHandler.ToggleTop)                      // 置顶
+		admin.POST("/post/:pid/move", adminHandler.MoveNode)                      // 移动节点
+		admin.POST("/user/:id/punish", adminHandler.PunishUser)                   // 惩罚用户
+		admin.DELETE("/post/:pid", adminHandler.AdminDeletePost)                  // 管理员删除文章
+		admin.DELETE("/comment/:cid", adminHandler.AdminDeleteComment)            // 管理员删除评论
+		admin.GET("/comment/:cid/revisions", adminHandler.CommentRevisions)       // 评论修订记录
+		admin.GET("/reports", adminHandler.ListReports)                           // 举报列表
+		admin.DELETE("/reports/:id", adminHandler.HandleReport)                   // 处理举报
+		admin.POST("/reports/:id/approve", adminHandler.ApproveReportedItem)      // 审核通过，恢复被折叠内容
+		admin.POST("/reports/:id/uphold", adminHandler.UpholdReportedItem)        // 确认违规
+		admin.GET("/users", adminHandler.ListUsers)                               // 用户管理
+		admin.GET("/vote-flags", adminHandler.ListVoteFlags)                      // 刷票嫌疑审核
+		admin.POST("/vote-flags/analyze", adminHandler.RunVoteAnalysis)           // 立即执行刷票分析
+		admin.POST("/vote-flags/:id/dismiss", adminHandler.DismissVoteFlag)       // 忽略嫌疑
+		admin.POST("/vote-flags/:id/neutralize", adminHandler.NeutralizeVoteFlag) // 剔除相关投票
+		admin.GET("/ranking/stats", adminHandler.RankingQueueStats)               // 排名更新队列指标
+
+		// 后台任务队列
+		admin.GET("/jobs", adminHandler.ListJobs)            // 任务列表
+		admin.POST("/jobs/:id/retry", adminHandler.RetryJob) // 重试死信任务
+		admin.DELETE("/jobs/:id", adminHandler.DeleteJob)    // 删除任务
+
+		// LLM 提示词
+		admin.GET("/prompts", adminHandler.ListPrompts)                                      // 提示词列表
+		admin.GET("/prompts/:name", adminHandler.EditPrompt)                                 // 编辑提示词
+		admin.POST("/prompts/:name", adminHandler.SavePrompt)                                // 保存为新版本
+		admin.POST("/prompts/:name/dry-run", adminHandler.DryRunPrompt)                      // 用样例帖子试运行
+		admin.POST("/prompts/:name/versions/:version/activate", adminHandler.ActivatePrompt) // 启用指定版本
+
+		// AI 评论审核
+		admin.GET("/moderation", adminHandler.ListCommentModerations)                // 审核队列
+		admin.POST("/moderation/:id/approve", adminHandler.ApproveCommentModeration) // 判定正常，恢复显示
+		admin.POST("/moderation/:id/uphold", adminHandler.UpholdCommentModeration)   // 确认违规
+
+		// AI 隔离的帖子与申诉
+		admin.GET("/appeals", adminHandler.ListAppeals)                         // 隔离与申诉列表
+		admin.POST("/appeals/:id/restore", adminHandler.RestoreQuarantinedPost) // 通过申诉，恢复帖子
+		admin.POST("/appeals/:id/reject", adminHandler.RejectAppeal)            // 驳回申诉
 	}
 }
@@ -0,0 +1,114 @@
+package searchquery
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"zhulink/internal/utils"
+)
+
+// Document 增量匹配时的一条新内容（帖子或评论）
+type Document struct {
+	Title     string
+	Body      string
+	Node      string // 所属节点名
+	Author    string // 作者用户名
+	URL       string // 帖子外链，讨论帖为空
+	CreatedAt time.Time
+}
+
+// Match 判断新内容是否满足查询，分词规则与站内全文检索一致。
+// 新内容还没有投票，score 过滤器不参与匹配。
+func (q Query) Match(doc Document) bool {
+	if q.Node != "" && q.Node != doc.Node {
+		return false
+	}
+	if q.Author != "" && !strings.EqualFold(q.Author, doc.Author) {
+		return false
+	}
+	if q.Site != "" && !matchSite(q.Site, doc.URL) {
+		return false
+	}
+	switch q.Type {
+	case TypeAsk:
+		if doc.URL != "" {
+			return false
+		}
+	case TypeLink:
+		if doc.URL == "" {
+			return false
+		}
+	}
+	if q.After != nil && doc.CreatedAt.Before(*q.After) {
+		return false
+	}
+	if q.Before != nil && !doc.CreatedAt.Before(*q.Before) {
+		return false
+	}
+
+	text := doc.Title + "\n" + doc.Body
+	tokens := make(map[string]bool)
+	for _, t := range strings.Fields(utils.SearchDocumentTokens(text)) {
+		tokens[t] = true
+	}
+	containsAll := func(s string) bool {
+		queryTokens := strings.Fields(utils.SearchQueryTokens(s))
+		for _, t := range queryTokens {
+			if !tokens[t] {
+				return false
+			}
+		}
+		return len(queryTokens) > 0
+	}
+
+	if keywords := q.Keywords(); keywords != "" && utils.SearchQueryTokens(keywords) != "" && !containsAll(keywords) {
+		return false
+	}
+	lower := strings.ToLower(text)
+	for _, phrase := range q.Phrases {
+		if !strings.Contains(lower, strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	for _, excluded := range q.Excluded {
+		if containsAll(excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSite 外链的域名是否为 site 或其子域名
+func matchSite(site, rawURL string) bool {
+	if rawURL == "" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == site || strings.HasSuffix(host, "."+site)
+}
+
+// SiteCandidates 外链域名及其各级父域名，即所有能匹配该链接的 site 过滤器值；讨论帖返回 nil
+func SiteCandidates(rawURL string) []string {
+	if rawURL == "" {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	var sites []string
+	for host != "" {
+		sites = append(sites, host)
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return sites
+}
@@ -0,0 +1,105 @@
+package searchquery
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	created := time.Date(2026, 1, 15, 10, 0, 0, 0, time.Local)
+	post := Document{
+		Title:     "Go 并发编程入门",
+		Body:      "介绍 goroutine 和 channel 的 Error Handling 实践",
+		Node:      "技术",
+		Author:    "Alice",
+		URL:       "https://gist.github.com/alice/123",
+		CreatedAt: created,
+	}
+	ask := post
+	ask.URL = ""
+
+	tests := []struct {
+		name  string
+		query string
+		doc   Document
+		want  bool
+	}{
+		{"空查询", "", post, true},
+		{"中文关键词", "并发", post, true},
+		{"中文关键词不连续不命中", "并编", post, false},
+		{"单字", "发", post, true},
+		{"英文关键词不区分大小写", "GOROUTINE", post, true},
+		{"所有关键词都要命中", "goroutine rust", post, false},
+		{"短语", `"error handling"`, post, true},
+		{"短语要求连续", `"handling error"`, post, false},
+		{"排除词命中", "并发 -channel", post, false},
+		{"排除词未命中", "并发 -广告", post, true},
+		{"排除短语", `-"并发编程"`, post, false},
+		{"节点", "node:技术", post, true},
+		{"其他节点", "node:生活", post, false},
+		{"作者不区分大小写", "author:alice", post, true},
+		{"其他作者", "author:bob", post, false},
+		{"site 匹配子域名", "site:github.com", post, true},
+		{"site 精确匹配", "site:gist.github.com", post, true},
+		{"site 不匹配相似域名", "site:hub.com", post, false},
+		{"讨论帖没有 site", "site:github.com", ask, false},
+		{"type:link", "type:link", post, true},
+		{"type:link 不匹配讨论帖", "type:link", ask, false},
+		{"type:ask", "type:ask", ask, true},
+		{"type:ask 不匹配链接", "type:ask", post, false},
+		{"after 含当天", "after:2026-01-15", post, true},
+		{"after 之前", "after:2026-01-16", post, false},
+		{"before 不含当天", "before:2026-01-15", post, false},
+		{"before 之后", "before:2026-01-16", post, true},
+		{"score 不参与匹配", "score:>100", post, true},
+		{"只有标点的关键词不限制", "!!", post, true},
+		{"过滤器和关键词组合", "node:技术 author:alice channel", post, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.query).Match(tt.doc); got != tt.want {
+				t.Errorf("Parse(%q).Match() = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSite(t *testing.T) {
+	tests := []struct {
+		site string
+		url  string
+		want bool
+	}{
+		{"github.com", "https://github.com/golang/go", true},
+		{"github.com", "https://WWW.GitHub.com/", true},
+		{"github.com", "https://github.com:8443/x", true},
+		{"github.com", "https://notgithub.com/", false},
+		{"github.com", "https://github.com.evil.io/", false},
+		{"github.com", "", false},
+		{"github.com", "://bad", false},
+	}
+	for _, tt := range tests {
+		if got := matchSite(tt.site, tt.url); got != tt.want {
+			t.Errorf("matchSite(%q, %q) = %v, want %v", tt.site, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSiteCandidates(t *testing.T) {
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://gist.GitHub.com/alice", []string{"gist.github.com", "github.com", "com"}},
+		{"https://github.com:8443/x", []string{"github.com", "com"}},
+		{"http://localhost/", []string{"localhost"}},
+		{"", nil},
+		{"://bad", nil},
+	}
+	for _, tt := range tests {
+		if got := SiteCandidates(tt.url); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SiteCandidates(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Package searchquery 解析站内搜索的查询语法。
+//
+// 支持的写法：
+//
+//	普通词            Go 并发
+//	"精确短语"        "error handling"
+//	-排除词           -广告  -"软文推广"
+//	node:节点名       node:技术
+//	author:用户名     author:alice
+//	site:域名         site:github.com（含子域名）
+//	type:ask|link     ask 为无链接的讨论帖，link 为分享链接
+//	after:日期        after:2026-01-01（含当天）
+//	before:日期       before:2026-02-01（不含当天）
+//	score:比较        score:>10、score:>=5、score:<0、score:3（等同 >=3）
+//	sort:排序         sort:relevance|new|top
+//
+// 同一个过滤器出现多次时以最后一次为准；无法识别的 key 或非法的值按普通词处理。
+package searchquery
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 帖子类型
+const (
+	TypeAsk  = "ask"  // 无外链的讨论帖
+	TypeLink = "link" // 分享链接
+)
+
+// 排序方式
+const (
+	SortRelevance = "relevance"
+	SortNew       = "new"
+	SortTop       = "top"
+)
+
+// DateLayout 日期过滤器的格式
+const DateLayout = "2006-01-02"
+
+// FilterKeys 可通过 key:value 语法或同名 URL 参数设置的过滤器
+var FilterKeys = []string{"node", "author", "site", "type", "after", "before", "score", "sort"}
+
+// ScoreFilter 帖子得分（净赞数）比较条件
+type ScoreFilter struct {
+	Op    string // >, >=, <, <=, =
+	Value int
+}
+
+// String 还原为查询语法中的写法，如 ">10"
+func (f ScoreFilter) String() string {
+	return f.Op + strconv.Itoa(f.Value)
+}
+
+// Query 解析后的搜索条件
+type Query struct {
+	Terms    []string // 普通词
+	Phrases  []string // 引号包裹的精确短语
+	Excluded []string // 以 - 开头的排除词或短语
+
+	Node   string
+	Author string
+	Site   string
+	Type   string
+	After  *time.Time
+	Before *time.Time
+	Score  *ScoreFilter
+	Sort   string
+}
+
+// Parse 解析查询字符串
+func Parse(input string) Query {
+	var q Query
+	for _, tok := range tokenize(input) {
+		switch {
+		case tok.negated:
+			if tok.text != "" {
+				q.Excluded = append(q.Excluded, tok.text)
+			}
+		case tok.quoted:
+			if tok.text != "" {
+				q.Phrases = append(q.Phrases, tok.text)
+			}
+		case tok.key != "":
+			if !q.Set(tok.key, tok.text) {
+				q.Terms = append(q.Terms, tok.raw)
+			}
+		default:
+			q.Terms = append(q.Terms, tok.text)
+		}
+	}
+	return q
+}
+
+// Set 设置一个过滤器，key 不存在或 value 非法时返回 false；value 为空表示清除该过滤器
+func (q *Query) Set(key, value string) bool {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(key) {
+	case "node":
+		q.Node = value
+	case "author":
+		q.Author = strings.TrimPrefix(value, "@")
+	case "site":
+		site, ok := normalizeSite(value)
+		if !ok {
+			return false
+		}
+		q.Site = site
+	case "type":
+		value = strings.ToLower(value)
+		if value != "" && value != TypeAsk && value != TypeLink {
+			return false
+		}
+		q.Type = value
+	case "after", "before":
+		var t *time.Time
+		if value != "" {
+			parsed, err := time.ParseInLocation(DateLayout, value, time.Local)
+			if err != nil {
+				return false
+			}
+			t = &parsed
+		}
+		if strings.ToLower(key) == "after" {
+			q.After = t
+		} else {
+			q.Before = t
+		}
+	case "score":
+		if value == "" {
+			q.Score = nil
+			return true
+		}
+		f, ok := parseScore(value)
+		if !ok {
+			return false
+		}
+		q.Score = &f
+	case "sort":
+		value = strings.ToLower(value)
+		if value != "" && value != SortRelevance && value != SortNew && value != SortTop {
+			return false
+		}
+		q.Sort = value
+	default:
+		return false
+	}
+	return true
+}
+
+// Keywords 参与全文检索和语义检索的文本（普通词和短语）
+func (q Query) Keywords() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases))
+	parts = append(parts, q.Terms...)
+	parts = append(parts, q.Phrases...)
+	return strings.Join(parts, " ")
+}
+
+// Text 查询中的文本部分（普通词、短语、排除词），即去掉过滤器后的查询字符串
+func (q Query) Text() string {
+	var parts []string
+	parts = append(parts, q.Terms...)
+	for _, p := range q.Phrases {
+		parts = append(parts, quote(p))
+	}
+	for _, e := range q.Excluded {
+		if strings.ContainsFunc(e, unicode.IsSpace) {
+			e = quote(e)
+		}
+		parts = append(parts, "-"+e)
+	}
+	return strings.Join(parts, " ")
+}
+
+// HasText 是否包含文本条件
+func (q Query) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0 || len(q.Excluded) > 0
+}
+
+// HasFilters 是否包含结构化过滤器（不含排序）
+func (q Query) HasFilters() bool {
+	return q.Node != "" || q.Author != "" || q.Site != "" || q.Type != "" ||
+		q.After != nil || q.Before != nil || q.Score != nil
+}
+
+// IsEmpty 没有任何检索条件
+func (q Query) IsEmpty() bool {
+	return !q.HasText() && !q.HasFilters()
+}
+
+// SortOrDefault 返回排序方式，未指定时为按相关度
+func (q Query) SortOrDefault() string {
+	if q.Sort == "" {
+		return SortRelevance
+	}
+	return q.Sort
+}
+
+// AfterDate 返回 after 过滤器的日期字符串，未设置时为空
+func (q Query) AfterDate() string {
+	if q.After == nil {
+		return ""
+	}
+	return q.After.Format(DateLayout)
+}
+
+// BeforeDate 返回 before 过滤器的日期字符串，未设置时为空
+func (q Query) BeforeDate() string {
+	if q.Before == nil {
+		return ""
+	}
+	return q.Before.Format(DateLayout)
+}
+
+// ScoreString 返回 score 过滤器的写法，未设置时为空
+func (q Query) ScoreString() string {
+	if q.Score == nil {
+		return ""
+	}
+	return q.Score.String()
+}
+
+// Values 把查询编码为 URL 参数：文本部分放在 q，过滤器各占一个参数
+func (q Query) Values() url.Values {
+	v := url.Values{}
+	v.Set("q", q.Text())
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("node", q.Node)
+	set("author", q.Author)
+	set("site", q.Site)
+	set("type", q.Type)
+	set("after", q.AfterDate())
+	set("before", q.BeforeDate())
+	set("score", q.ScoreString())
+	set("sort", q.Sort)
+	return v
+}
+
+// String 还原为完整的查询语法
+func (q Query) String() string {
+	parts := []string{}
+	if text := q.Text(); text != "" {
+		parts = append(parts, text)
+	}
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if strings.ContainsFunc(value, unicode.IsSpace) {
+			value = quote(value)
+		}
+		parts = append(parts, key+":"+value)
+	}
+	add("node", q.Node)
+	add("author", q.Author)
+	add("site", q.Site)
+	add("type", q.Type)
+	add("after", q.AfterDate())
+	add("before", q.BeforeDate())
+	add("score", q.ScoreString())
+	add("sort", q.Sort)
+	return strings.Join(parts, " ")
+}
+
+// token 词法分析得到的一个片段
+type token struct {
+	raw     string // 原始写法，过滤器非法时作为普通词
+	key     string // key:value 中的 key
+	text    string // 词、短语或过滤器的值
+	quoted  bool
+	negated bool
+}
+
+// tokenize 按空白切分，引号内的空白不切分；识别 -前缀 和 key:value
+func tokenize(input string) []token {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		var tok token
+
+		// 排除前缀（单独的 "-" 视为普通词）
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negated = true
+			i++
+		}
+
+		// key:"value" 或 key:value
+		if !tok.negated && !isQuote(runes[i]) {
+			if j := indexKeySeparator(runes, i); j > i {
+				key := string(runes[i:j])
+				if isFilterKey(key) {
+					tok.key = strings.ToLower(key)
+					i = j + 1
+				}
+			}
+		}
+
+		if i < len(runes) && isQuote(runes[i]) {
+			value, next := readQuoted(runes, i)
+			tok.text = value
+			tok.quoted = tok.key == ""
+			i = next
+		} else {
+			next := i
+			for next < len(runes) && !unicode.IsSpace(runes[next]) {
+				next++
+			}
+			tok.text = string(runes[i:next])
+			i = next
+		}
+		tok.raw = string(runes[start:i])
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// indexKeySeparator 返回从 i 开始的非空白片段中第一个冒号的位置，没有则返回 -1
+func indexKeySeparator(runes []rune, i int) int {
+	for j := i; j < len(runes) && !unicode.IsSpace(runes[j]); j++ {
+		if runes[j] == ':' {
+			return j
+		}
+	}
+	return -1
+}
+
+// readQuoted 读取从 i 处引号开始的短语，缺少右引号时读到结尾
+func readQuoted(runes []rune, i int) (string, int) {
+	closing := matchingQuote(runes[i])
+	j := i + 1
+	for j < len(runes) && runes[j] != closing {
+		j++
+	}
+	value := strings.Join(strings.Fields(string(runes[i+1:j])), " ")
+	if j < len(runes) {
+		j++ // 跳过右引号
+	}
+	return value, j
+}
+
+// isQuote 是否为短语的左引号（支持中文引号）
+func isQuote(r rune) bool {
+	return r == '"' || r == '“'
+}
+
+// matchingQuote 返回与左引号配对的右引号
+func matchingQuote(r rune) rune {
+	if r == '“' {
+		return '”'
+	}
+	return '"'
+}
+
+// isFilterKey 是否为支持的过滤器
+func isFilterKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range FilterKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScore 解析 ">10"、">=5"、"<0"、"=3"、"3"
+func parseScore(value string) (ScoreFilter, bool) {
+	op := ">="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return ScoreFilter{}, false
+	}
+	return ScoreFilter{Op: op, Value: n}, true
+}
+
+// normalizeSite 把 "https://www.GitHub.com/x" 之类的写法规范为 "www.github.com"
+func normalizeSite(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	value = strings.ToLower(value)
+	if i := strings.Index(value, "://"); i >= 0 {
+		value = value[i+3:]
+	}
+	if i := strings.IndexAny(value, "/?#"); i >= 0 {
+		value = value[:i]
+	}
+	if value == "" || strings.ContainsAny(value, " @") {
+		return "", false
+	}
+	return value, true
+}
+
+// quote 给短语加上双引号（短语内的双引号替换为空格）
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, " ") + `"`
+}
@@ -0,0 +1,217 @@
+package searchquery
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) *time.Time {
+	t, err := time.ParseInLocation(DateLayout, s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{"空查询", "   ", Query{}},
+		{"普通词", "Go  并发", Query{Terms: []string{"Go", "并发"}}},
+		{"短语", `"error  handling" go`, Query{Terms: []string{"go"}, Phrases: []string{"error handling"}}},
+		{"中文引号短语", "“依赖 注入” 框架", Query{Terms: []string{"框架"}, Phrases: []string{"依赖 注入"}}},
+		{"缺少右引号读到结尾", `go "error handling`, Query{Terms: []string{"go"}, Phrases: []string{"error handling"}}},
+		{"空短语忽略", `"" go`, Query{Terms: []string{"go"}}},
+		{"排除词", "go -广告", Query{Terms: []string{"go"}, Excluded: []string{"广告"}}},
+		{"排除短语", `-"软文 推广" go`, Query{Terms: []string{"go"}, Excluded: []string{"软文 推广"}}},
+		{"排除中文引号短语", "-“软文 推广”", Query{Excluded: []string{"软文 推广"}}},
+		{"单独的减号是普通词", "a - b", Query{Terms: []string{"a", "-", "b"}}},
+		{"过滤器", "node:技术 author:@alice type:ASK sort:new",
+			Query{Node: "技术", Author: "alice", Type: TypeAsk, Sort: SortNew}},
+		{"key 不区分大小写", "NODE:技术", Query{Node: "技术"}},
+		{"引号包裹的值", `node:"Go 语言" 入门`, Query{Terms: []string{"入门"}, Node: "Go 语言"}},
+		{"中文引号包裹的值", "node:“Go 语言”", Query{Node: "Go 语言"}},
+		{"site 规范化", "site:https://www.GitHub.com/golang/go", Query{Site: "www.github.com"}},
+		{"日期", "after:2026-01-01 before:2026-02-01",
+			Query{After: date("2026-01-01"), Before: date("2026-02-01")}},
+		{"score 默认为 >=", "score:3", Query{Score: &ScoreFilter{Op: ">=", Value: 3}}},
+		{"score 比较", "score:<=-2", Query{Score: &ScoreFilter{Op: "<=", Value: -2}}},
+		{"未知 key 按普通词", "foo:bar go", Query{Terms: []string{"foo:bar", "go"}}},
+		{"URL 按普通词", "https://example.com", Query{Terms: []string{"https://example.com"}}},
+		{"非法的值按普通词", "type:video after:2026-13-01 score:abc",
+			Query{Terms: []string{"type:video", "after:2026-13-01", "score:abc"}}},
+		{"非法的引号值保留原始写法", `sort:"hot one"`, Query{Terms: []string{`sort:"hot one"`}}},
+		{"重复的过滤器以最后一次为准", "node:技术 node:生活", Query{Node: "生活"}},
+		{"排除前缀不识别过滤器", "-node:技术", Query{Excluded: []string{"node:技术"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	inputs := []string{
+		"Go 并发",
+		`"error handling" -广告 -"软文 推广"`,
+		"“依赖 注入” 框架",
+		`node:"Go 语言" author:alice site:github.com type:link`,
+		"after:2026-01-01 before:2026-02-01 score:>10 sort:top",
+		"score:3",
+		"foo:bar type:video",
+		"-node:技术 入门",
+	}
+	for _, input := range inputs {
+		q := Parse(input)
+		if got := Parse(q.String()); !reflect.DeepEqual(got, q) {
+			t.Errorf("Parse(%q).String() = %q, reparsed as %+v, want %+v", input, q.String(), got, q)
+		}
+	}
+}
+
+func TestQuerySet(t *testing.T) {
+	tests := []struct {
+		name  string
+		start Query
+		key   string
+		value string
+		ok    bool
+		want  Query
+	}{
+		{"设置节点", Query{}, "node", " 技术 ", true, Query{Node: "技术"}},
+		{"作者去掉 @", Query{}, "author", "@alice", true, Query{Author: "alice"}},
+		{"key 不区分大小写", Query{}, "Sort", "TOP", true, Query{Sort: SortTop}},
+		{"空值清除过滤器", Query{Node: "技术", Score: &ScoreFilter{Op: ">", Value: 1}}, "score", "", true, Query{Node: "技术"}},
+		{"空值清除日期", Query{After: date("2026-01-01")}, "after", "", true, Query{}},
+		{"未知 key", Query{}, "tag", "go", false, Query{}},
+		{"非法类型不修改", Query{Type: TypeAsk}, "type", "video", false, Query{Type: TypeAsk}},
+		{"非法日期不修改", Query{Before: date("2026-02-01")}, "before", "2026/02/01", false, Query{Before: date("2026-02-01")}},
+		{"非法排序", Query{}, "sort", "hot", false, Query{}},
+		{"非法域名", Query{}, "site", "alice@example.com", false, Query{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.start
+			if ok := q.Set(tt.key, tt.value); ok != tt.ok {
+				t.Errorf("Set(%q, %q) = %v, want %v", tt.key, tt.value, ok, tt.ok)
+			}
+			if !reflect.DeepEqual(q, tt.want) {
+				t.Errorf("after Set(%q, %q) query = %+v, want %+v", tt.key, tt.value, q, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ScoreFilter
+		ok    bool
+	}{
+		{">10", ScoreFilter{">", 10}, true},
+		{">=5", ScoreFilter{">=", 5}, true},
+		{"<0", ScoreFilter{"<", 0}, true},
+		{"<=-2", ScoreFilter{"<=", -2}, true},
+		{"=3", ScoreFilter{"=", 3}, true},
+		{"3", ScoreFilter{">=", 3}, true},
+		{"> 4", ScoreFilter{">", 4}, true},
+		{">", ScoreFilter{}, false},
+		{"abc", ScoreFilter{}, false},
+		{">>1", ScoreFilter{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseScore(tt.value)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseScore(%q) = %+v, %v, want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeSite(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"github.com", "github.com", true},
+		{"GitHub.com", "github.com", true},
+		{"https://www.github.com/golang/go", "www.github.com", true},
+		{"example.com?x=1", "example.com", true},
+		{"example.com#top", "example.com", true},
+		{"https://", "", false},
+		{"alice@example.com", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeSite(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeSite(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestQueryText(t *testing.T) {
+	tests := []struct {
+		q    Query
+		want string
+	}{
+		{Query{}, ""},
+		{Query{Terms: []string{"Go", "并发"}, Node: "技术"}, "Go 并发"},
+		{Query{Phrases: []string{"error handling"}}, `"error handling"`},
+		{Query{Phrases: []string{`say "hi"`}}, `"say  hi "`},
+		{Query{Excluded: []string{"广告", "软文 推广"}}, `-广告 -"软文 推广"`},
+		{Query{Terms: []string{"go"}, Phrases: []string{"a b"}, Excluded: []string{"c"}}, `go "a b" -c`},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Text(); got != tt.want {
+			t.Errorf("%+v.Text() = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	q := Query{
+		Terms:    []string{"入门"},
+		Excluded: []string{"广告"},
+		Node:     "Go 语言",
+		Author:   "alice",
+		After:    date("2026-01-01"),
+		Score:    &ScoreFilter{Op: ">", Value: 10},
+		Sort:     SortNew,
+	}
+	want := `入门 -广告 node:"Go 语言" author:alice after:2026-01-01 score:>10 sort:new`
+	if got := q.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (Query{}).String(); got != "" {
+		t.Errorf("empty Query String() = %q, want empty", got)
+	}
+}
+
+func TestQueryValues(t *testing.T) {
+	q := Parse(`go -广告 node:技术 site:github.com after:2026-01-01 score:5`)
+	want := url.Values{
+		"q":     {"go -广告"},
+		"node":  {"技术"},
+		"site":  {"github.com"},
+		"after": {"2026-01-01"},
+		"score": {">=5"},
+	}
+	if got := q.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+
+	// 文本为空时 q 参数仍然存在，保证表单回显
+	if got := (Query{}).Values(); !reflect.DeepEqual(got, url.Values{"q": {""}}) {
+		t.Errorf("empty Query Values() = %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 评论删除后的占位内容
+const (
+	CommentDeletedContent      = "该评论已删除。"
+	CommentAdminDeletedContent = "该评论已被管理员删除。"
+)
+
+// 评论内容错误
+var (
+	ErrCommentEmpty    = errors.New("评论内容不能为空")
+	ErrCommentReserved = errors.New("评论内容不能与删除占位内容相同")
+)
+
+// CommentEditWindows 各等级评论发布后的可编辑时长（按 utils.GetUserLevel 的等级名称配置），
+// 可通过 COMMENT_EDIT_WINDOWS 覆盖，见 LoadCommentEditWindows
+var CommentEditWindows = map[string]time.Duration{
+	"萌芽": 5 * time.Minute,
+	"破土": 10 * time.Minute,
+	"新竹": 30 * time.Minute,
+	"翠竹": 2 * time.Hour,
+	"成林": 24 * time.Hour,
+}
+
+// LoadCommentEditWindows 从环境变量覆盖各等级的可编辑时长（启动时调用一次），
+// 格式 COMMENT_EDIT_WINDOWS="萌芽:5m,成林:24h"，只覆盖列出的等级，不合法的项忽略
+func LoadCommentEditWindows() {
+	for level, value := range utils.ParseLevelMap(os.Getenv("COMMENT_EDIT_WINDOWS")) {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.Printf("[Comment] 忽略不合法的 COMMENT_EDIT_WINDOWS 配置 %s:%s", level, value)
+			continue
+		}
+		CommentEditWindows[level] = d
+	}
+}
+
+// GetCommentEditWindow 根据积分返回评论可编辑时长
+func GetCommentEditWindow(points int) time.Duration {
+	levelName, _ := utils.GetUserLevel(points)
+	return CommentEditWindows[levelName]
+}
+
+// IsCommentDeleted 判断评论是否已被（软）删除
+func IsCommentDeleted(comment *models.Comment) bool {
+	return comment.Content == CommentDeletedContent || comment.Content == CommentAdminDeletedContent
+}
+
+// ValidateCommentBody 检查评论正文：不能为空，也不能与删除占位内容相同（否则会被当作已删除）
+func ValidateCommentBody(body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return ErrCommentEmpty
+	}
+	if body == CommentDeletedContent || body == CommentAdminDeletedContent {
+		return ErrCommentReserved
+	}
+	return nil
+}
+
+// CanEditComment 检查用户是否可以编辑该评论
+func CanEditComment(user *models.User, comment *models.Comment) error {
+	if comment.UserID != user.ID {
+		return errors.New("只能编辑自己的评论")
+	}
+	if IsCommentDeleted(comment) {
+		return errors.New("评论已删除，无法编辑")
+	}
+	if time.Since(comment.CreatedAt) > GetCommentEditWindow(user.Points) {
+		return errors.New("已超过可编辑时间")
+	}
+	return nil
+}
+
+// SplitReplyPrefix 拆分评论开头由系统拼接的回复引用（↳ 回复 [#楼层 @用户](#comment-ID)）
+func SplitReplyPrefix(content string) (prefix, body string) {
+	if !strings.HasPrefix(content, "↳ 回复 ") {
+		return "", content
+	}
+	idx := strings.Index(content, "\n\n")
+	if idx == -1 {
+		return "", content
+	}
+	return content[:idx+2], content[idx+2:]
+}
+
+// EditComment 使用事务保存修订记录并更新评论内容
+// 回复引用前缀由系统维护，编辑时保持不变；正文不合法时返回 ErrCommentEmpty 或 ErrCommentReserved
+func EditComment(comment *models.Comment, editorID uint, newBody string) error {
+	if err := ValidateCommentBody(newBody); err != nil {
+		return err
+	}
+
+	prefix, _ := SplitReplyPrefix(comment.Content)
+	newContent := prefix + newBody
+
+	if newContent == comment.Content {
+		return nil
+	}
+
+	now := time.Now()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 1. 保存编辑前的内容
+		revision := models.CommentRevision{
+			CommentID: comment.ID,
+			EditorID:  editorID,
+			Content:   comment.Content,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		// 2. 更新评论内容和编辑标记
+		return tx.Model(&models.Comment{}).
+			Where("id = ?", comment.ID).
+			Updates(map[string]interface{}{
+				"content":    newContent,
+				"edited_at":  &now,
+				"edit_count": gorm.Expr("edit_count + 1"),
+			}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	comment.Content = newContent
+	comment.EditedAt = &now
+	comment.EditCount++
+	return nil
+}
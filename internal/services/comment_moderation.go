@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CommentModerationThreshold 某等级作者的评论审核阈值（模型置信度 0-1），为 0 表示不采取该动作
+type CommentModerationThreshold struct {
+	Review float64 // 达到该值进入管理员审核队列
+	Hide   float64 // 达到该值自动折叠评论，等待审核
+}
+
+// CommentModerationThresholds 各等级作者的审核阈值（按 utils.GetUserLevel 的等级名称配置）。
+// 等级越高越宽松：翠竹只进审核队列、不自动折叠，成林不送审。
+var CommentModerationThresholds = map[string]CommentModerationThreshold{
+	"萌芽": {Review: 0.5, Hide: 0.8},
+	"破土": {Review: 0.6, Hide: 0.85},
+	"新竹": {Review: 0.7, Hide: 0.9},
+	"翠竹": {Review: 0.85},
+	"成林": {},
+}
+
+// LoadCommentModerationThresholds 从环境变量覆盖各等级的审核阈值（启动时调用一次），
+// 格式 COMMENT_MODERATION_THRESHOLDS="萌芽:0.5/0.8,翠竹:0.85/0"（送审/折叠，省略折叠表示不自动折叠），
+// 只覆盖列出的等级，不合法的项忽略
+func LoadCommentModerationThresholds() {
+	for level, value := range utils.ParseLevelMap(os.Getenv("COMMENT_MODERATION_THRESHOLDS")) {
+		reviewValue, hideValue, _ := strings.Cut(value, "/")
+		review, err := parseModerationConfidence(reviewValue)
+		hide, hideErr := parseModerationConfidence(hideValue)
+		if err != nil || hideErr != nil {
+			log.Printf("[Moderation] 忽略不合法的 COMMENT_MODERATION_THRESHOLDS 配置 %s:%s", level, value)
+			continue
+		}
+		CommentModerationThresholds[level] = CommentModerationThreshold{Review: review, Hide: hide}
+	}
+}
+
+// parseModerationConfidence 解析 0-1 之间的置信度阈值，空字符串为 0
+func parseModerationConfidence(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > 1 {
+		return 0, fmt.Errorf("置信度 %v 超出 0-1 范围", v)
+	}
+	return v, nil
+}
+
+// CommentVerdict 模型对评论的判定
+type CommentVerdict struct {
+	Category      string  `json:"category"`   // none, spam, abuse, off_topic
+	Confidence    float64 `json:"confidence"` // 0-1
+	Reason        string  `json:"reason"`
+	PromptVersion int     `json:"-"`
+}
+
+// commentModerationAction 按阈值决定采取的动作，返回空字符串表示不处理。
+// 离题只是质量问题，最多进入审核队列，不自动折叠。
+func commentModerationAction(threshold CommentModerationThreshold, verdict *CommentVerdict) string {
+	if verdict.Category == models.CommentCategoryNone {
+		return ""
+	}
+	if threshold.Hide > 0 && verdict.Confidence >= threshold.Hide && verdict.Category != models.CommentCategoryOffTopic {
+		return models.CommentModerationHide
+	}
+	if threshold.Review > 0 && verdict.Confidence >= threshold.Review {
+		return models.CommentModerationReview
+	}
+	return ""
+}
+
+// ClassifyComment 用启用中的 comment_moderation 提示词判断评论是否违规（JSON 模式）
+func (s *LLMService) ClassifyComment(ctx context.Context, title, content, comment string) (*CommentVerdict, error) {
+	if !s.Configured() {
+		return nil, fmt.Errorf("LLM_TOKEN 未配置")
+	}
+
+	prompt, err := RenderPrompt(PromptCommentModeration, PromptVars{"Title": title, "Content": content, "Comment": comment})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Complete(ctx, prompt.Request())
+	if err != nil {
+		return nil, err
+	}
+
+	verdict, err := parseCommentModerationResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	verdict.PromptVersion = prompt.Version
+	return verdict, nil
+}
+
+// ModerateComment 用 AI 审核一条评论：达到作者等级对应的阈值时写入审核队列，置信度高时折叠评论。
+// 编辑后重新审核判定为正常时，撤销该评论待处理的审核记录（并恢复因此被折叠的评论）。
+// 返回评论所在帖子的 pid，以及评论的显示状态是否改变（需要刷新详情页缓存）。
+func ModerateComment(ctx context.Context, commentID uint) (pid string, changed bool, err error) {
+	llm := GetLLMService()
+	if !llm.Configured() {
+		return "", false, nil
+	}
+
+	var comment models.Comment
+	if err := db.DB.Preload("Post").Preload("User").First(&comment, commentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil // 评论已删除
+		}
+		return "", false, err
+	}
+	pid = comment.Post.Pid
+	if IsCommentDeleted(&comment) || comment.ModerationState == models.ModerationRemoved {
+		return pid, false, nil
+	}
+
+	level, _ := utils.GetUserLevel(comment.User.Points)
+	threshold := CommentModerationThresholds[level]
+	if comment.User.Role == "admin" || (threshold.Review <= 0 && threshold.Hide <= 0) {
+		return pid, false, nil
+	}
+
+	_, body := SplitReplyPrefix(comment.Content)
+	verdict, err := llm.ClassifyComment(ctx, comment.Post.Title, comment.Post.Content, body)
+	if err != nil {
+		return pid, false, err
+	}
+
+	action := commentModerationAction(threshold, verdict)
+	if action == "" {
+		changed, err = withdrawCommentModeration(&comment)
+		if changed {
+			IndexPostAsync(comment.PostID)
+		}
+		return pid, changed, err
+	}
+
+	record := models.CommentModeration{
+		CommentID:     comment.ID,
+		PostID:        comment.PostID,
+		UserID:        comment.UserID,
+		Category:      verdict.Category,
+		Confidence:    verdict.Confidence,
+		Reason:        truncateRunes(verdict.Reason, 500),
+		Action:        action,
+		AuthorLevel:   level,
+		PromptVersion: verdict.PromptVersion,
+		Status:        models.CommentModerationPending,
+	}
+	// 同一评论只保留一条记录，编辑后重新审核覆盖上次的结果并重新进入队列
+	if err := db.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "comment_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"category":       record.Category,
+			"confidence":     record.Confidence,
+			"reason":         record.Reason,
+			"action":         record.Action,
+			"author_level":   record.AuthorLevel,
+			"prompt_version": record.PromptVersion,
+			"status":         record.Status,
+			"resolved_at":    nil,
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&record).Error; err != nil {
+		return pid, false, err
+	}
+
+	if action == models.CommentModerationHide {
+		// 只折叠正常显示的评论，管理员审核恢复过（approved）的评论不再自动折叠
+		res := db.DB.Model(&models.Comment{}).
+			Where("id = ? AND moderation_state = ?", comment.ID, models.ModerationVisible).
+			Update("moderation_state", models.ModerationHidden)
+		if res.Error != nil {
+			return pid, false, res.Error
+		}
+		changed = res.RowsAffected > 0
+		if changed {
+			IndexPostAsync(comment.PostID)
+		}
+	}
+	log.Printf("[Moderation] 评论 %d 判定为 %s（置信度 %.2f，作者等级 %s），动作: %s", comment.ID, verdict.Category, verdict.Confidence, level, action)
+	return pid, changed, nil
+}
+
+// withdrawCommentModeration 删除评论待处理的审核记录；若评论是被 AI 折叠的且举报分未达到阈值，恢复显示
+func withdrawCommentModeration(comment *models.Comment) (bool, error) {
+	var record models.CommentModeration
+	if err := db.DB.Where("comment_id = ? AND status = ?", comment.ID, models.CommentModerationPending).
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := db.DB.Delete(&record).Error; err != nil {
+		return false, err
+	}
+	if record.Action != models.CommentModerationHide || comment.ModerationState != models.ModerationHidden ||
+		comment.FlagScore >= FlagHideThreshold() {
+		return false, nil
+	}
+	res := db.DB.Model(&models.Comment{}).
+		Where("id = ? AND moderation_state = ?", comment.ID, models.ModerationHidden).
+		Update("moderation_state", models.ModerationVisible)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ResolveCommentModeration 管理员处理审核记录：approve 为 true 时恢复评论显示（同时驳回针对该评论的举报），
+// 否则确认违规。返回评论所在帖子的 pid。
+func ResolveCommentModeration(id uint, approve bool) (string, error) {
+	var record models.CommentModeration
+	if err := db.DB.Preload("Comment.Post").First(&record, id).Error; err != nil {
+		return "", err
+	}
+
+	status := models.CommentModerationUpheld
+	if approve {
+		status = models.CommentModerationApproved
+		if _, err := ApproveFlaggedItem("comment", record.CommentID); err != nil {
+			return "", err
+		}
+	} else if err := UpholdFlaggedItem("comment", record.CommentID); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(&record).Updates(map[string]interface{}{
+		"status":      status,
+		"resolved_at": &now,
+	}).Error; err != nil {
+		return "", err
+	}
+	return record.Comment.Post.Pid, nil
+}
+
+func parseCommentModerationResponse(responseContent string) (*CommentVerdict, error) {
+	responseContent = strings.TrimSpace(responseContent)
+	startIdx := strings.Index(responseContent, "{")
+	endIdx := strings.LastIndex(responseContent, "}")
+	if startIdx == -1 || endIdx == -1 || startIdx > endIdx {
+		log.Printf("[LLM-Moderation] 无法解析响应: %s", responseContent)
+		return nil, fmt.Errorf("invalid JSON response")
+	}
+
+	var verdict CommentVerdict
+	if err := json.Unmarshal([]byte(responseContent[startIdx:endIdx+1]), &verdict); err != nil {
+		return nil, fmt.Errorf("parse comment verdict failed: %v", err)
+	}
+
+	verdict.Category = strings.ToLower(strings.TrimSpace(verdict.Category))
+	switch verdict.Category {
+	case models.CommentCategoryNone, models.CommentCategorySpam, models.CommentCategoryAbuse, models.CommentCategoryOffTopic:
+	case "":
+		verdict.Category = models.CommentCategoryNone
+	default:
+		return nil, fmt.Errorf("unknown comment category %q", verdict.Category)
+	}
+	if verdict.Confidence < 0 {
+		verdict.Confidence = 0
+	} else if verdict.Confidence > 1 {
+		verdict.Confidence = 1
+	}
+	return &verdict, nil
+}
+
+// commentModerationSystemPrompt 评论审核的默认系统提示词（comment_moderation 提示词第 1 版）
+const commentModerationSystemPrompt = `# Role
+你是一个中文技术社区的评论审核员。你会看到一篇帖子（标题和正文摘要）和该帖子下的一条评论，判断这条评论是否违规。
+
+# Categories
+- spam：垃圾信息或广告引流，如推销产品/服务、留联系方式拉群、博彩灰产、与讨论无关的外链堆砌。
+- abuse：辱骂、人身攻击、歧视或仇恨言论、骚扰。
+- off_topic：与帖子内容完全无关的灌水，如无意义字符、刷屏。
+- none：正常评论。
+
+# Guidelines
+- **存疑从宽**：观点尖锐、批评帖子或其他人的观点、简短的附和（如"学到了"、"+1"）、正常推荐相关工具或链接，都属于 none。
+- 只有明确违规时才给出高置信度；拿不准时置信度应低于 0.5。
+- 只根据评论本身判断，帖子内容仅用于判断是否离题。
+
+# Output Requirements
+严格按照以下 JSON 格式返回，不要包含任何其他文字：
+{"category":"none|spam|abuse|off_topic","confidence":0.0-1.0,"reason":"一句话说明理由"}
+`
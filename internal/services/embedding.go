@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// 向量服务提供方
+const (
+	EmbeddingProviderOllama = "ollama" // Ollama /api/embeddings
+	EmbeddingProviderOpenAI = "openai" // OpenAI 兼容的 /embeddings
+)
+
+// defaultEmbeddingDimensions 未配置 EMBEDDING_DIMENSIONS 时的向量维度（bge-base-zh、nomic-embed-text 均为 768）
+const defaultEmbeddingDimensions = 768
+
+// ErrEmbeddingDisabled 向量服务因配置与数据库不一致被停用
+var ErrEmbeddingDisabled = errors.New("向量服务已停用")
+
+// EmbeddingProvider 文本向量化接口，不同服务的请求格式由实现处理；ctx 取消或超时时请求随之中止
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// EmbeddingConfig 向量服务配置
+type EmbeddingConfig struct {
+	Provider   string
+	BaseURL    string
+	Token      string
+	Model      string
+	Dimensions int
+}
+
+// EmbeddingService 向量服务：选择提供方、校验维度，并记录每个向量由哪个模型生成
+type EmbeddingService struct {
+	config   EmbeddingConfig
+	provider EmbeddingProvider
+	disabled atomic.Bool
+}
+
+var (
+	embeddingService *EmbeddingService
+	embeddingOnce    sync.Once
+)
+
+// GetEmbeddingService 按环境变量初始化向量服务。
+// 兼容旧配置：未设置 EMBEDDING_* 时使用 OLLAMA_BASE_URL / OLLAMA_MODEL。
+func GetEmbeddingService() *EmbeddingService {
+	embeddingOnce.Do(func() {
+		config := EmbeddingConfig{
+			Provider: strings.ToLower(os.Getenv("EMBEDDING_PROVIDER")),
+			BaseURL:  os.Getenv("EMBEDDING_BASE_URL"),
+			Token:    os.Getenv("EMBEDDING_TOKEN"),
+			Model:    os.Getenv("EMBEDDING_MODEL"),
+		}
+		if config.Provider == "" {
+			config.Provider = EmbeddingProviderOllama
+		}
+
+		switch config.Provider {
+		case EmbeddingProviderOpenAI:
+			if config.BaseURL == "" {
+				config.BaseURL = "https://api.openai.com/v1"
+			}
+			if config.Model == "" {
+				config.Model = "text-embedding-3-small"
+			}
+		default:
+			if config.Provider != EmbeddingProviderOllama {
+				log.Printf("[Embedding] 未知的 EMBEDDING_PROVIDER=%q，使用 ollama", config.Provider)
+				config.Provider = EmbeddingProviderOllama
+			}
+			if config.BaseURL == "" {
+				config.BaseURL = os.Getenv("OLLAMA_BASE_URL")
+			}
+			if config.BaseURL == "" {
+				config.BaseURL = "http://localhost:11434"
+			}
+			if config.Model == "" {
+				config.Model = os.Getenv("OLLAMA_MODEL")
+			}
+			if config.Model == "" {
+				config.Model = "quentinz/bge-base-zh-v1.5"
+			}
+		}
+
+		config.Dimensions = defaultEmbeddingDimensions
+		if v := os.Getenv("EMBEDDING_DIMENSIONS"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				config.Dimensions = n
+			} else {
+				log.Printf("[Embedding] EMBEDDING_DIMENSIONS=%q 无效，使用默认值 %d", v, defaultEmbeddingDimensions)
+			}
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		var provider EmbeddingProvider
+		if config.Provider == EmbeddingProviderOpenAI {
+			provider = &openAIEmbeddingProvider{baseURL: config.BaseURL, token: config.Token, model: config.Model, client: client}
+		} else {
+			provider = &ollamaEmbeddingProvider{baseURL: config.BaseURL, model: config.Model, client: client}
+		}
+
+		embeddingService = &EmbeddingService{
+			config:   config,
+			provider: provider,
+		}
+	})
+	return embeddingService
+}
+
+// Config 返回当前向量服务配置
+func (s *EmbeddingService) Config() EmbeddingConfig {
+	return s.config
+}
+
+// ModelID 当前模型的标识（提供方:模型名），写入 posts.embedding_model，只有相同标识的向量才能互相比较
+func (s *EmbeddingService) ModelID() string {
+	return s.config.Provider + ":" + s.config.Model
+}
+
+// Dimensions 当前配置的向量维度
+func (s *EmbeddingService) Dimensions() int {
+	return s.config.Dimensions
+}
+
+// Enabled 向量服务是否可用（启动校验未通过时停用）
+func (s *EmbeddingService) Enabled() bool {
+	return !s.disabled.Load()
+}
+
+// Embed 获取文本向量，并校验维度与配置一致
+func (s *EmbeddingService) Embed(ctx context.Context, text string) ([]float32, error) {
+	if s.disabled.Load() {
+		return nil, ErrEmbeddingDisabled
+	}
+	embedding, err := s.provider.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedding) != s.config.Dimensions {
+		return nil, fmt.Errorf("模型 %s 返回 %d 维向量，与 EMBEDDING_DIMENSIONS=%d 不一致", s.ModelID(), len(embedding), s.config.Dimensions)
+	}
+	return embedding, nil
+}
+
+// Validate 启动时校验：embedding 列维度与配置一致才启用向量服务，否则停用并提示迁移。
+// 同时统计尚未用当前模型生成向量的帖子。
+func (s *EmbeddingService) Validate() error {
+	dims, err := db.EnsureEmbeddingColumn(s.config.Dimensions)
+	if err != nil {
+		s.disabled.Store(true)
+		return fmt.Errorf("检查 posts.embedding 列失败: %w", err)
+	}
+	if dims != s.config.Dimensions {
+		s.disabled.Store(true)
+		return fmt.Errorf("posts.embedding 为 %d 维，当前模型 %s 配置为 %d 维，向量服务已停用；请运行 go run ./cmd/embeddings -resize 迁移",
+			dims, s.ModelID(), s.config.Dimensions)
+	}
+
+	var stale int64
+	db.DB.Model(&models.Post{}).
+		Where("embedding IS NOT NULL AND embedding_model <> ?", s.ModelID()).
+		Count(&stale)
+	if stale > 0 {
+		log.Printf("[Embedding] %d 篇帖子的向量不是由当前模型 %s 生成，不参与语义检索和相关推荐；请运行 go run ./cmd/embeddings 重新生成", stale, s.ModelID())
+	}
+	log.Printf("[Embedding] 向量服务: %s, %d 维", s.ModelID(), s.config.Dimensions)
+	return nil
+}
+
+// PostVectorText 拼接用于生成帖子向量的文本（正文只取前 200 字）
+func PostVectorText(title, keywords, description, content string) string {
+	return fmt.Sprintf("标题：%s\n关键词：%s\n摘要：%s\n正文：%s", title, keywords, description, truncateRunes(content, 200))
+}
+
+// EmbedPostFields 生成帖子向量，返回需要写入 posts 的字段（向量、拼接文本、模型和维度）
+func (s *EmbeddingService) EmbedPostFields(ctx context.Context, vectorText string) (map[string]interface{}, error) {
+	embedding, err := s.Embed(ctx, vectorText)
+	if err != nil {
+		return nil, err
+	}
+	vec := pgvector.NewVector(embedding)
+	return map[string]interface{}{
+		"vector_text":     vectorText,
+		"embedding":       &vec,
+		"embedding_model": s.ModelID(),
+		"embedding_dim":   len(embedding),
+	}, nil
+}
+
+// ollamaEmbeddingProvider 调用 Ollama 的 /api/embeddings
+type ollamaEmbeddingProvider struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func (p *ollamaEmbeddingProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	reqBody := struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+	}{Model: p.model, Prompt: text}
+
+	var embeddingResp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/api/embeddings"
+	if err := postEmbeddingJSON(ctx, p.client, apiURL, "", reqBody, &embeddingResp); err != nil {
+		log.Printf("[Ollama] %v", err)
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	if len(embeddingResp.Embedding) == 0 {
+		return nil, fmt.Errorf("received empty embedding from ollama")
+	}
+	return embeddingResp.Embedding, nil
+}
+
+// openAIEmbeddingProvider 调用 OpenAI 兼容的 /embeddings（OpenAI、vLLM、各类网关等）
+type openAIEmbeddingProvider struct {
+	baseURL string
+	token   string
+	model   string
+	client  *http.Client
+}
+
+func (p *openAIEmbeddingProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	reqBody := struct {
+		Model string `json:"model"`
+		Input string `json:"input"`
+	}{Model: p.model, Input: text}
+
+	var embeddingResp struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/embeddings"
+	if err := postEmbeddingJSON(ctx, p.client, apiURL, p.token, reqBody, &embeddingResp); err != nil {
+		log.Printf("[Embedding] %v", err)
+		return nil, fmt.Errorf("openai embeddings: %w", err)
+	}
+	if len(embeddingResp.Data) == 0 || len(embeddingResp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("received empty embedding from %s", apiURL)
+	}
+	return embeddingResp.Data[0].Embedding, nil
+}
+
+// postEmbeddingJSON 发送 JSON 请求并解析 JSON 响应
+func postEmbeddingJSON(ctx context.Context, client *http.Client, apiURL, token string, reqBody, out interface{}) error {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("marshal request failed: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("create request failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("api request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api returned non-200 status: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"log"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+)
+
+// EmbeddingBackfillOptions 向量补建/重建参数
+type EmbeddingBackfillOptions struct {
+	BatchSize int                               // 每批读取的帖子数
+	Limit     int                               // 最多处理的帖子数，0 表示不限
+	Progress  func(done, failed, pending int64) // 每批结束后回调
+}
+
+// EmbeddingBackfillStats 补建结果
+type EmbeddingBackfillStats struct {
+	Done   int64
+	Failed int64
+}
+
+// PendingEmbeddingCount 尚未用当前模型生成向量的帖子数
+func (s *EmbeddingService) PendingEmbeddingCount() int64 {
+	var count int64
+	db.DB.Model(&models.Post{}).
+		Where("embedding IS NULL OR embedding_model <> ?", s.ModelID()).
+		Count(&count)
+	return count
+}
+
+// AdoptLegacyEmbeddings 把未记录模型的历史向量标记为当前模型生成（维度一致时），返回标记的数量。
+// 仅在确认历史向量就是由当前模型生成时使用，否则应重新生成。
+func (s *EmbeddingService) AdoptLegacyEmbeddings() (int64, error) {
+	result := db.DB.Model(&models.Post{}).
+		Where("embedding IS NOT NULL AND embedding_model = '' AND vector_dims(embedding) = ?", s.config.Dimensions).
+		Updates(map[string]interface{}{
+			"embedding_model": s.ModelID(),
+			"embedding_dim":   s.config.Dimensions,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// BackfillEmbeddings 为没有向量或向量来自其他模型的帖子（重新）生成向量。
+// 按 ID 递增分批处理，已完成的帖子带有当前模型标识，中断后重新运行会从剩余的帖子继续。
+func (s *EmbeddingService) BackfillEmbeddings(ctx context.Context, opts EmbeddingBackfillOptions) (EmbeddingBackfillStats, error) {
+	var stats EmbeddingBackfillStats
+	if !s.Enabled() {
+		return stats, ErrEmbeddingDisabled
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+
+	var lastID uint
+	for {
+		var posts []models.Post
+		err := db.DB.Select("id, title, content, seo_keywords, seo_description").
+			Where("id > ? AND (embedding IS NULL OR embedding_model <> ?)", lastID, s.ModelID()).
+			Order("id ASC").
+			Limit(opts.BatchSize).
+			Find(&posts).Error
+		if err != nil {
+			return stats, err
+		}
+		if len(posts) == 0 {
+			return stats, nil
+		}
+
+		for _, post := range posts {
+			select {
+			case <-ctx.Done():
+				return stats, ctx.Err()
+			default:
+			}
+			if opts.Limit > 0 && stats.Done+stats.Failed >= int64(opts.Limit) {
+				return stats, nil
+			}
+			lastID = post.ID
+
+			vectorText := PostVectorText(post.Title, post.SEOKeywords, post.SEODescription, post.Content)
+			fields, err := s.EmbedPostFields(ctx, vectorText)
+			if err == nil {
+				err = db.DB.Model(&models.Post{}).Where("id = ?", post.ID).Updates(fields).Error
+			}
+			if err != nil {
+				log.Printf("[Embedding] 帖子 %d 生成向量失败: %v", post.ID, err)
+				stats.Failed++
+				continue
+			}
+			stats.Done++
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(stats.Done, stats.Failed, s.PendingEmbeddingCount())
+		}
+	}
+}
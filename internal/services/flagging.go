@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultFlagHideThreshold 默认自动折叠阈值：约等于 3 个「新竹」用户的举报
+const DefaultFlagHideThreshold = 3.0
+
+// ErrAlreadyReported 同一用户重复举报同一内容
+var ErrAlreadyReported = errors.New("already reported")
+
+// FlagHideThreshold 返回自动折叠阈值（FLAG_HIDE_THRESHOLD），未配置或不合法时使用默认值
+func FlagHideThreshold() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("FLAG_HIDE_THRESHOLD"), 64); err == nil && v > 0 {
+		return v
+	}
+	return DefaultFlagHideThreshold
+}
+
+// ReporterWeight 举报者的信任权重，与投票权重规则一致（按等级和账号年龄）
+func ReporterWeight(user *models.User) float64 {
+	return utils.VoteWeight(user.Points, user.CreatedAt)
+}
+
+// FlagResult 提交举报后的内容状态
+type FlagResult struct {
+	FlagScore  float64
+	AutoHidden bool // 本次举报使内容达到阈值并被自动折叠
+}
+
+// SubmitReport 记录一条举报并重新计算被举报内容的举报分。
+// 举报分为所有待处理举报的权重之和，首次达到阈值时内容从 visible 转为 hidden；
+// 管理员审核恢复过（approved）的内容不会再次被自动折叠。
+func SubmitReport(reporter *models.User, itemType string, itemID uint, itemPid, reason string) (*FlagResult, error) {
+	_, model, err := voteTarget(itemType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FlagResult{}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定被举报内容，串行化同一内容上的并发举报
+		var state string
+		if err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("moderation_state").Where("id = ?", itemID).Scan(&state).Error; err != nil {
+			return err
+		}
+		if state == "" {
+			return gorm.ErrRecordNotFound
+		}
+
+		var exists int64
+		tx.Model(&models.Report{}).
+			Where("user_id = ? AND item_type = ? AND item_id = ?", reporter.ID, itemType, itemID).
+			Count(&exists)
+		if exists > 0 {
+			return ErrAlreadyReported
+		}
+
+		report := models.Report{
+			UserID:   reporter.ID,
+			ItemType: itemType,
+			ItemID:   itemID,
+			ItemPid:  itemPid,
+			Reason:   reason,
+			Weight:   ReporterWeight(reporter),
+			Status:   models.ReportStatusPending,
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+
+		score, err := recalculateFlagScore(tx, itemType, itemID)
+		if err != nil {
+			return err
+		}
+		result.FlagScore = score
+
+		if state == models.ModerationVisible && score >= FlagHideThreshold() {
+			if err := tx.Model(model).Where("id = ?", itemID).
+				Update("moderation_state", models.ModerationHidden).Error; err != nil {
+				return err
+			}
+			result.AutoHidden = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.AutoHidden && itemType == "comment" {
+		indexCommentPostAsync(itemID)
+	}
+	return result, nil
+}
+
+// RecalculateFlagScore 重新汇总内容的举报分（例如管理员删除单条举报后）
+func RecalculateFlagScore(itemType string, itemID uint) (float64, error) {
+	if _, _, err := voteTarget(itemType); err != nil {
+		return 0, err
+	}
+	return recalculateFlagScore(db.DB, itemType, itemID)
+}
+
+func recalculateFlagScore(tx *gorm.DB, itemType string, itemID uint) (float64, error) {
+	_, model, _ := voteTarget(itemType)
+
+	var score float64
+	if err := tx.Model(&models.Report{}).
+		Select("COALESCE(SUM(weight), 0)").
+		Where("item_type = ? AND item_id = ? AND status = ?", itemType, itemID, models.ReportStatusPending).
+		Scan(&score).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(model).Where("id = ?", itemID).UpdateColumn("flag_score", score).Error; err != nil {
+		return 0, err
+	}
+	return score, nil
+}
+
+// ApproveFlaggedItem 管理员审核通过：恢复内容显示，驳回所有待处理举报，并扣除举报者积分
+// 返回被处罚的举报者人数
+func ApproveFlaggedItem(itemType string, itemID uint) (int, error) {
+	_, model, err := voteTarget(itemType)
+	if err != nil {
+		return 0, err
+	}
+
+	var flaggers []uint
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(model).Where("id = ?", itemID).Updates(map[string]interface{}{
+			"moderation_state": models.ModerationApproved,
+			"flag_score":       0,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		pending := tx.Model(&models.Report{}).
+			Where("item_type = ? AND item_id = ? AND status = ?", itemType, itemID, models.ReportStatusPending).
+			Session(&gorm.Session{})
+		if err := pending.Distinct().Pluck("user_id", &flaggers).Error; err != nil {
+			return err
+		}
+		if err := pending.Update("status", models.ReportStatusRejected).Error; err != nil {
+			return err
+		}
+
+		for _, userID := range flaggers {
+			if err := addPointsTx(tx, userID, PointsFalseReport, ActionFalseReport); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if itemType == "comment" {
+		indexCommentPostAsync(itemID)
+	}
+	return len(flaggers), nil
+}
+
+// UpholdFlaggedItem 管理员确认违规：内容标记为 removed，所有待处理举报标记为成立
+func UpholdFlaggedItem(itemType string, itemID uint) error {
+	_, model, err := voteTarget(itemType)
+	if err != nil {
+		return err
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(model).Where("id = ?", itemID).Updates(map[string]interface{}{
+			"moderation_state": models.ModerationRemoved,
+			"flag_score":       0,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.Report{}).
+			Where("item_type = ? AND item_id = ? AND status = ?", itemType, itemID, models.ReportStatusPending).
+			Update("status", models.ReportStatusUpheld).Error
+	})
+	if err == nil && itemType == "comment" {
+		indexCommentPostAsync(itemID)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -60,50 +61,70 @@ func (s *IndexNowService) GetKeyLocation() string {
 	return s.keyLocation
 }
 
-// SubmitURL asynchronously submits a single URL to IndexNow
-// This is a fire-and-forget operation that runs in a goroutine
+// indexNowJob IndexNow 提交任务的参数
+type indexNowJob struct {
+	Pid string `json:"pid"`
+}
+
+// SubmitURL queues a single post URL for IndexNow submission
+// The request is sent by the job queue and retried with backoff on failure
 func (s *IndexNowService) SubmitURL(postPid string) {
 	// Skip if API key is not configured
 	if s.apiKey == "" {
 		return
 	}
 
-	// Execute in goroutine to avoid blocking the main flow
-	go func() {
-		s.semaphore <- struct{}{}
-		defer func() { <-s.semaphore }()
+	EnqueueJob(JobIndexNowSubmit, indexNowJob{Pid: postPid}, JobOptions{DedupeKey: "indexnow:" + postPid})
+}
 
-		postURL := fmt.Sprintf("%s/p/%s", s.siteURL, postPid)
+// runIndexNowJob submits the queued URL; 429 and 5xx are retried, other 4xx are permanent
+func runIndexNowJob(ctx context.Context, payload []byte) error {
+	var job indexNowJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
 
-		payload := map[string]interface{}{
-			"host":        strings.TrimPrefix(s.siteURL, "https://"),
-			"key":         s.apiKey,
-			"keyLocation": s.keyLocation,
-			"urlList":     []string{postURL},
-		}
+	s := GetIndexNowService()
+	if s.apiKey == "" {
+		return nil
+	}
 
-		jsonData, err := json.Marshal(payload)
-		if err != nil {
-			log.Printf("[IndexNow] JSON marshaling failed: %v", err)
-			return
-		}
+	s.semaphore <- struct{}{}
+	defer func() { <-s.semaphore }()
 
-		resp, err := s.client.Post(
-			"https://api.indexnow.org/IndexNow",
-			"application/json",
-			bytes.NewBuffer(jsonData),
-		)
+	postURL := fmt.Sprintf("%s/p/%s", s.siteURL, job.Pid)
 
-		if err != nil {
-			log.Printf("[IndexNow] Submission failed: %v", err)
-			return
-		}
-		defer resp.Body.Close()
+	body := map[string]interface{}{
+		"host":        strings.TrimPrefix(s.siteURL, "https://"),
+		"key":         s.apiKey,
+		"keyLocation": s.keyLocation,
+		"urlList":     []string{postURL},
+	}
 
-		if resp.StatusCode == http.StatusOK {
-			log.Printf("[IndexNow] Successfully submitted: %s", postURL)
-		} else {
-			log.Printf("[IndexNow] API returned non-200 status: %s", resp.Status)
-		}
-	}()
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return PermanentJobError(fmt.Errorf("JSON marshaling failed: %v", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.indexnow.org/IndexNow", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return PermanentJobError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("submission failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted:
+		log.Printf("[IndexNow] Successfully submitted: %s", postURL)
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("API returned status: %s", resp.Status)
+	default:
+		return PermanentJobError(fmt.Errorf("API returned status: %s", resp.Status))
+	}
 }
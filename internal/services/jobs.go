@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm/clause"
+)
+
+// 后台任务类型
+const (
+	JobPostMeta          = "post.meta"           // 生成帖子 SEO 元数据、向量并识别广告（处理函数由 handlers 注册）
+	JobIndexNowSubmit    = "indexnow.submit"     // 向 IndexNow 提交帖子链接
+	JobSendMail          = "mail.send"           // 发送邮件
+	JobSavedSearchDigest = "saved_search.digest" // 发送保存的搜索每日邮件摘要
+	JobCommentModerate   = "comment.moderate"    // AI 审核评论（处理函数由 handlers 注册）
+	JobThreadDigest      = "thread.digest"       // 生成评论区讨论要点
+)
+
+// 后台任务队列参数
+const (
+	jobPollInterval       = 2 * time.Second    // 空闲时轮询队列的间隔（消化延迟任务、重试任务和其他实例写入的任务）
+	jobMaintainInterval   = time.Minute        // 回收超时任务、清理已完成任务的间隔
+	jobStaleTimeout       = 15 * time.Minute   // 执行超过该时长仍未结束，视为进程已崩溃，放回队列
+	jobRetention          = 7 * 24 * time.Hour // 已完成任务的保留时长
+	jobBaseBackoff        = time.Minute        // 第一次重试的等待时间，之后每次翻倍
+	jobMaxBackoff         = time.Hour
+	defaultJobWorkers     = 3
+	defaultJobMaxAttempts = 5
+	defaultJobTimeout     = 5 * time.Minute
+	jobShutdownTimeout    = 10 * time.Second
+)
+
+// JobHandler 执行一个任务。返回错误时按指数退避重试，次数用尽后进入死信状态；
+// ctx 在超时或服务停止时取消。
+type JobHandler func(ctx context.Context, payload []byte) error
+
+// JobTypeConfig 任务类型的执行参数，零值使用默认值
+type JobTypeConfig struct {
+	MaxAttempts int           // 最多执行次数
+	Timeout     time.Duration // 单次执行的超时时间
+	Sensitive   bool          // 执行成功后清空参数（如邮件正文中的验证码）
+}
+
+// JobOptions 入队参数
+type JobOptions struct {
+	DedupeKey string        // 去重键：已有等待中的同键任务时不再新建
+	Delay     time.Duration // 延迟执行
+	Debounce  bool          // 已有等待中的同键任务时，改为本次的参数和执行时间（防抖）
+}
+
+// permanentJobError 不应重试的错误
+type permanentJobError struct {
+	err error
+}
+
+func (e *permanentJobError) Error() string { return e.err.Error() }
+func (e *permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError 包装不可重试的错误（如参数无效），任务直接进入死信状态
+func PermanentJobError(err error) error {
+	return &permanentJobError{err: err}
+}
+
+// JobTypeStats 某类任务各状态的数量
+type JobTypeStats struct {
+	Type      string `json:"type"`
+	Pending   int64  `json:"pending"`
+	Running   int64  `json:"running"`
+	Succeeded int64  `json:"succeeded"`
+	Dead      int64  `json:"dead"`
+}
+
+type registeredJobType struct {
+	handler JobHandler
+	config  JobTypeConfig
+}
+
+// JobQueue 基于 jobs 表的后台任务队列。
+// 多个 worker（可跨实例）通过 FOR UPDATE SKIP LOCKED 领取任务，进程崩溃或重启后任务仍在表中继续执行。
+type JobQueue struct {
+	mu      sync.RWMutex
+	types   map[string]registeredJobType
+	wake    chan struct{} // 有新任务时唤醒 worker
+	wg      sync.WaitGroup
+	started bool
+}
+
+var (
+	jobQueue     *JobQueue
+	jobQueueOnce sync.Once
+)
+
+// GetJobQueue 获取单例任务队列，并注册 services 内部的任务类型
+func GetJobQueue() *JobQueue {
+	jobQueueOnce.Do(func() {
+		jobQueue = &JobQueue{
+			types: make(map[string]registeredJobType),
+			wake:  make(chan struct{}, 1),
+		}
+		jobQueue.Register(JobIndexNowSubmit, runIndexNowJob, JobTypeConfig{Timeout: time.Minute})
+		jobQueue.Register(JobSendMail, runSendMailJob, JobTypeConfig{Timeout: time.Minute, Sensitive: true})
+		jobQueue.Register(JobSavedSearchDigest, runSavedSearchDigestJob, JobTypeConfig{Timeout: 10 * time.Minute})
+		jobQueue.Register(JobThreadDigest, runThreadDigestJob, JobTypeConfig{MaxAttempts: 3, Timeout: 2 * time.Minute})
+	})
+	return jobQueue
+}
+
+// Register 注册任务类型的处理函数，须在 Start 之前调用
+func (q *JobQueue) Register(jobType string, handler JobHandler, config JobTypeConfig) {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultJobMaxAttempts
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultJobTimeout
+	}
+	q.mu.Lock()
+	q.types[jobType] = registeredJobType{handler: handler, config: config}
+	q.mu.Unlock()
+}
+
+func (q *JobQueue) jobType(jobType string) (registeredJobType, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	t, ok := q.types[jobType]
+	return t, ok
+}
+
+// Enqueue 写入一个任务，payload 序列化为 JSON
+func (q *JobQueue) Enqueue(jobType string, payload interface{}, opts JobOptions) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化任务参数失败: %w", err)
+	}
+
+	maxAttempts := defaultJobMaxAttempts
+	if t, ok := q.jobType(jobType); ok {
+		maxAttempts = t.config.MaxAttempts
+	}
+
+	job := models.Job{
+		Type:        jobType,
+		DedupeKey:   opts.DedupeKey,
+		Payload:     string(data),
+		Status:      models.JobStatusPending,
+		RunAt:       time.Now().Add(opts.Delay),
+		MaxAttempts: maxAttempts,
+	}
+
+	tx := db.DB
+	if opts.DedupeKey != "" {
+		// 与 idx_jobs_dedupe_pending 的条件一致，才能命中部分唯一索引
+		conflict := clause.OnConflict{
+			Columns:     []clause.Column{{Name: "dedupe_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = 'pending' AND dedupe_key <> ''"}}},
+			DoNothing:   true,
+		}
+		if opts.Debounce {
+			conflict.DoNothing = false
+			conflict.DoUpdates = clause.AssignmentColumns([]string{"payload", "run_at", "updated_at"})
+		}
+		tx = tx.Clauses(conflict)
+	}
+	if err := tx.Create(&job).Error; err != nil {
+		return err
+	}
+
+	if opts.Delay <= 0 {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// EnqueueJob 写入任务，失败时记录日志（调用方无需处理错误的场景）
+func EnqueueJob(jobType string, payload interface{}, opts JobOptions) {
+	if err := GetJobQueue().Enqueue(jobType, payload, opts); err != nil {
+		log.Printf("[Jobs] 任务入队失败 (type=%s, key=%s): %v", jobType, opts.DedupeKey, err)
+	}
+}
+
+// Start 启动 worker（JOB_WORKERS 个，默认 3）和维护协程，ctx 取消后停止
+func (q *JobQueue) Start(ctx context.Context) {
+	workers := defaultJobWorkers
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			workers = n
+		}
+	}
+
+	q.mu.Lock()
+	if q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = true
+	q.mu.Unlock()
+
+	// 上次崩溃时未执行完的任务放回队列（正常停止时已由 worker 放回）
+	q.recoverStale(jobStaleTimeout)
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+	q.wg.Add(1)
+	go q.maintain(ctx)
+	log.Printf("[Jobs] 后台任务队列已启动，%d 个 worker", workers)
+}
+
+// Shutdown 等待正在执行的任务结束（ctx 取消后由 worker 放回队列）
+func (q *JobQueue) Shutdown() {
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("[Jobs] 后台任务队列已停止，剩余任务保留在队列中")
+	case <-time.After(jobShutdownTimeout):
+		log.Println("[Jobs] 等待任务结束超时，未完成的任务将在下次启动时重新执行")
+	}
+}
+
+// worker 领取并执行到期的任务：有新任务时立即处理，否则定时轮询
+func (q *JobQueue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := claimJob()
+			if err != nil {
+				log.Printf("[Jobs] 领取任务失败: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// maintain 定期回收超时任务并清理过期的已完成任务
+func (q *JobQueue) maintain(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(jobMaintainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		q.recoverStale(jobStaleTimeout)
+		db.DB.Where("status = ? AND finished_at < ?", models.JobStatusSucceeded, time.Now().Add(-jobRetention)).
+			Delete(&models.Job{})
+	}
+}
+
+// claimJob 领取一个到期的任务并标记为执行中，没有任务时返回 nil
+func claimJob() (*models.Job, error) {
+	now := time.Now()
+	var jobs []models.Job
+	err := db.DB.Raw(`UPDATE jobs SET status = ?, attempts = attempts + 1, locked_at = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs WHERE status = ? AND run_at <= ?
+			ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, models.JobStatusRunning, now, now, models.JobStatusPending, now).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// run 执行任务并按结果更新状态
+func (q *JobQueue) run(ctx context.Context, job *models.Job) {
+	t, ok := q.jobType(job.Type)
+	if !ok {
+		q.fail(job, PermanentJobError(fmt.Errorf("未注册的任务类型 %q", job.Type)))
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	err := safeRunJob(jobCtx, t.handler, []byte(job.Payload))
+	cancel()
+
+	switch {
+	case err == nil:
+		fields := map[string]interface{}{
+			"status":      models.JobStatusSucceeded,
+			"finished_at": time.Now(),
+			"locked_at":   nil,
+			"last_error":  "",
+		}
+		if t.config.Sensitive {
+			fields["payload"] = ""
+		}
+		db.DB.Model(&models.Job{}).Where("id = ?", job.ID).Updates(fields)
+	case ctx.Err() != nil:
+		// 服务停止导致的中断不计入重试次数
+		job.Attempts--
+		q.requeue(job, time.Now(), "服务停止，任务中断")
+	default:
+		q.fail(job, err)
+	}
+}
+
+// safeRunJob 执行处理函数，panic 视为执行失败
+func safeRunJob(ctx context.Context, handler JobHandler, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// fail 记录失败：还有重试次数时按指数退避放回队列，否则进入死信状态
+func (q *JobQueue) fail(job *models.Job, err error) {
+	var permanent *permanentJobError
+	if errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts {
+		log.Printf("[Jobs] 任务 %d (%s) 失败 %d 次，进入死信状态: %v", job.ID, job.Type, job.Attempts, err)
+		now := time.Now()
+		db.DB.Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":      models.JobStatusDead,
+			"last_error":  err.Error(),
+			"locked_at":   nil,
+			"finished_at": now,
+		})
+		return
+	}
+
+	backoff := jobBackoff(job.Attempts)
+	log.Printf("[Jobs] 任务 %d (%s) 第 %d 次执行失败，%v 后重试: %v", job.ID, job.Type, job.Attempts, backoff.Round(time.Second), err)
+	q.requeue(job, time.Now().Add(backoff), err.Error())
+}
+
+// requeue 放回等待队列。已有等待中的同键任务时由它代替执行，本任务直接结束
+func (q *JobQueue) requeue(job *models.Job, runAt time.Time, lastError string) {
+	result := db.DB.Model(&models.Job{}).
+		Where("id = ?", job.ID).
+		Where("dedupe_key = '' OR NOT EXISTS (SELECT 1 FROM jobs j WHERE j.dedupe_key = jobs.dedupe_key AND j.status = ?)", models.JobStatusPending).
+		Updates(map[string]interface{}{
+			"status":     models.JobStatusPending,
+			"attempts":   job.Attempts,
+			"run_at":     runAt,
+			"last_error": lastError,
+			"locked_at":  nil,
+		})
+	if result.Error == nil && result.RowsAffected > 0 {
+		return
+	}
+	db.DB.Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":      models.JobStatusSucceeded,
+		"last_error":  "已由等待中的同类任务代替: " + lastError,
+		"locked_at":   nil,
+		"finished_at": time.Now(),
+	})
+}
+
+// recoverStale 把执行时间超过 timeout 的任务放回队列（执行它的进程可能已崩溃）
+func (q *JobQueue) recoverStale(timeout time.Duration) {
+	var stale []models.Job
+	db.DB.Where("status = ? AND locked_at < ?", models.JobStatusRunning, time.Now().Add(-timeout)).Find(&stale)
+	for i := range stale {
+		log.Printf("[Jobs] 任务 %d (%s) 执行中断，重新放回队列", stale[i].ID, stale[i].Type)
+		if stale[i].Attempts >= stale[i].MaxAttempts {
+			q.fail(&stale[i], errors.New("执行超时或进程中断"))
+			continue
+		}
+		q.requeue(&stale[i], time.Now(), "执行超时或进程中断")
+	}
+}
+
+// jobBackoff 第 attempts 次失败后的等待时间：1、2、4、8... 分钟，最长 1 小时，带 ±20% 抖动
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobBaseBackoff
+	for i := 1; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > jobMaxBackoff {
+		backoff = jobMaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff)/5*2+1)) - backoff/5
+	return backoff + jitter
+}
+
+// Stats 按任务类型统计各状态的任务数
+func (q *JobQueue) Stats() []JobTypeStats {
+	var rows []struct {
+		Type   string
+		Status string
+		Count  int64
+	}
+	db.DB.Model(&models.Job{}).Select("type, status, COUNT(*) AS count").Group("type, status").Order("type").Scan(&rows)
+
+	stats := make([]JobTypeStats, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Type]
+		if !ok {
+			i = len(stats)
+			index[row.Type] = i
+			stats = append(stats, JobTypeStats{Type: row.Type})
+		}
+		switch row.Status {
+		case models.JobStatusPending:
+			stats[i].Pending = row.Count
+		case models.JobStatusRunning:
+			stats[i].Running = row.Count
+		case models.JobStatusSucceeded:
+			stats[i].Succeeded = row.Count
+		case models.JobStatusDead:
+			stats[i].Dead = row.Count
+		}
+	}
+	return stats
+}
+
+// List 按状态列出最近的任务
+func (q *JobQueue) List(status string, limit int) []models.Job {
+	var jobs []models.Job
+	db.DB.Where("status = ?", status).Order("updated_at DESC").Limit(limit).Find(&jobs)
+	return jobs
+}
+
+// Retry 把死信任务重新放回队列，重新计算重试次数
+func (q *JobQueue) Retry(id uint) error {
+	result := db.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusDead).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusPending,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"finished_at": nil,
+		})
+	if result.Error != nil {
+		// 只有命中 idx_jobs_dedupe_pending 才说明已有等待中的同键任务
+		var pgErr *pgconn.PgError
+		if errors.As(result.Error, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_jobs_dedupe_pending" {
+			return errors.New("已有等待中的同类任务")
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("任务不存在或不在死信状态")
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Delete 删除未在执行中的任务
+func (q *JobQueue) Delete(id uint) error {
+	result := db.DB.Where("id = ? AND status <> ?", id, models.JobStatusRunning).Delete(&models.Job{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("任务不存在或正在执行")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// 实时事件类型
+const (
+	LiveEventCommentCreated = "comment_created"
+	LiveEventCommentDeleted = "comment_deleted"
+	LiveEventVote           = "vote"
+)
+
+// liveNotifyChannel Postgres LISTEN/NOTIFY 使用的频道名
+const liveNotifyChannel = "zhulink_live"
+
+// LiveEvent 推送给文章详情页的实时事件
+type LiveEvent struct {
+	PostID    uint   `json:"post_id"`
+	Type      string `json:"type"`
+	CommentID uint   `json:"comment_id,omitempty"`
+	ItemType  string `json:"item_type,omitempty"` // 投票事件："post" 或 "comment"
+	ItemID    uint   `json:"item_id,omitempty"`
+	Upvotes   int64  `json:"upvotes"`
+	Downvotes int64  `json:"downvotes"`
+	Origin    string `json:"origin"` // 发布事件的实例 ID，用于跨实例同步时去重
+}
+
+// LiveHub 进程内的发布/订阅中心，按文章 ID 分发事件
+// 通过 Postgres LISTEN/NOTIFY 在多个实例之间同步
+type LiveHub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan LiveEvent]struct{}
+	instanceID  string
+	closing     chan struct{} // 服务关闭时关闭，通知所有长连接结束
+	closeOnce   sync.Once
+}
+
+var (
+	liveHub     *LiveHub
+	liveHubOnce sync.Once
+)
+
+// GetLiveHub 获取单例实时推送中心
+func GetLiveHub() *LiveHub {
+	liveHubOnce.Do(func() {
+		liveHub = &LiveHub{
+			subscribers: make(map[uint]map[chan LiveEvent]struct{}),
+			instanceID:  utils.RandStringBytesMaskImpr(12),
+			closing:     make(chan struct{}),
+		}
+	})
+	return liveHub
+}
+
+// Done 服务开始关闭时关闭的通道，长连接收到后应立即结束
+func (h *LiveHub) Done() <-chan struct{} {
+	return h.closing
+}
+
+// Shutdown 通知所有实时推送连接结束（注册为 http.Server 的 OnShutdown 回调，
+// 否则 SSE 连接永不空闲，srv.Shutdown 只能等到超时）
+func (h *LiveHub) Shutdown() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// Subscribe 订阅某篇文章的事件，返回事件通道和取消订阅函数
+func (h *LiveHub) Subscribe(postID uint) (<-chan LiveEvent, func()) {
+	ch := make(chan LiveEvent, 16)
+
+	h.mu.Lock()
+	if h.subscribers[postID] == nil {
+		h.subscribers[postID] = make(map[chan LiveEvent]struct{})
+	}
+	h.subscribers[postID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		delete(h.subscribers[postID], ch)
+		if len(h.subscribers[postID]) == 0 {
+			delete(h.subscribers, postID)
+		}
+		h.mu.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+// Publish 发布事件：先分发给本实例的订阅者，再通过 NOTIFY 广播给其他实例
+func (h *LiveHub) Publish(ev LiveEvent) {
+	ev.Origin = h.instanceID
+	h.broadcast(ev)
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if err := db.DB.Exec("SELECT pg_notify(?, ?)", liveNotifyChannel, string(payload)).Error; err != nil {
+		log.Printf("实时事件 NOTIFY 失败: %v", err)
+	}
+}
+
+// broadcast 非阻塞地分发给本实例订阅者，慢客户端的事件直接丢弃
+func (h *LiveHub) broadcast(ev LiveEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[ev.PostID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// StartPGBridge 启动 LISTEN 循环，将其他实例发布的事件转发给本实例订阅者
+// 连接断开后自动重连，直到 ctx 取消
+func (h *LiveHub) StartPGBridge(ctx context.Context) {
+	go func() {
+		for {
+			if err := h.listen(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("实时事件 LISTEN 连接中断: %v，5 秒后重连", err)
+			}
+			select {
+			case <-ctx.Done():
+				log.Println("实时事件同步已停止")
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+}
+
+func (h *LiveHub) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, db.DSN())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+liveNotifyChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var ev LiveEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &ev); err != nil {
+			continue
+		}
+		// 本实例发布的事件已在 Publish 中分发过
+		if ev.Origin == h.instanceID {
+			continue
+		}
+		h.broadcast(ev)
+	}
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,17 +13,23 @@ import (
 )
 
 type LLMConfig struct {
-	BaseURL       string
-	Model         string
-	Token         string
-	OllamaBaseURL string
-	OllamaModel   string
+	BaseURL string
+	Model   string
+	Token   string
+
+	// Cloudflare AI Gateway，配置后优先使用
+	GatewayURL   string
+	GatewayModel string
+	GatewayToken string
 }
 
 type LLMService struct {
-	config    LLMConfig
-	client    *http.Client
-	semaphore chan struct{}
+	config   LLMConfig
+	provider LLMProvider // 未配置任何模型时为 nil
+
+	// 服务关闭时取消所有进行中的请求和重试等待
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type ChatMessage struct {
@@ -31,11 +37,6 @@ type ChatMessage struct {
 	Content string `json:"content"`
 }
 
-type ChatRequest struct {
-	Model    string        `json:"model,omitempty"`
-	Messages []ChatMessage `json:"messages"`
-}
-
 type ChatResponse struct {
 	Choices []struct {
 		Message struct {
@@ -52,11 +53,12 @@ var (
 func GetLLMService() *LLMService {
 	llmOnce.Do(func() {
 		config := LLMConfig{
-			BaseURL:       os.Getenv("LLM_BASE_URL"),
-			Model:         os.Getenv("LLM_MODEL"),
-			Token:         os.Getenv("LLM_TOKEN"),
-			OllamaBaseURL: os.Getenv("OLLAMA_BASE_URL"),
-			OllamaModel:   os.Getenv("OLLAMA_MODEL"),
+			BaseURL:      os.Getenv("LLM_BASE_URL"),
+			Model:        os.Getenv("LLM_MODEL"),
+			Token:        os.Getenv("LLM_TOKEN"),
+			GatewayURL:   os.Getenv("CF_GATEWAY_URL"),
+			GatewayModel: os.Getenv("CF_GATEWAY_MODEL"),
+			GatewayToken: os.Getenv("CF_API_TOKEN"),
 		}
 
 		if config.BaseURL == "" {
@@ -65,143 +67,103 @@ func GetLLMService() *LLMService {
 		if config.Model == "" {
 			config.Model = "gemini-1.5-flash"
 		}
-		if config.OllamaBaseURL == "" {
-			config.OllamaBaseURL = "http://localhost:11434"
+		if config.GatewayModel == "" {
+			config.GatewayModel = config.Model
+		}
+
+		// 不设整体超时：非流式请求在 provider 内按次限时，流式请求由 ctx 控制
+		client := &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 30 * time.Second,
+			},
 		}
-		if config.OllamaModel == "" {
-			config.OllamaModel = "quentinz/bge-base-zh-v1.5"
+		semaphore := make(chan struct{}, 5)
+
+		var provider LLMProvider
+		switch {
+		case config.GatewayURL != "" && config.GatewayToken != "":
+			provider = &openAIChatProvider{name: "cf-gateway", baseURL: config.GatewayURL, model: config.GatewayModel, token: config.GatewayToken, client: client, semaphore: semaphore}
+		case config.Token != "":
+			provider = &openAIChatProvider{name: "llm", baseURL: config.BaseURL, model: config.Model, token: config.Token, client: client, semaphore: semaphore}
 		}
 
+		ctx, cancel := context.WithCancel(context.Background())
 		llmService = &LLMService{
-			config: config,
-			client: &http.Client{
-				Timeout: 30 * time.Second,
-			},
-			semaphore: make(chan struct{}, 5),
+			config:   config,
+			provider: provider,
+			ctx:      ctx,
+			cancel:   cancel,
 		}
 	})
 	return llmService
 }
 
-// callLLM 通用 OpenAI 兼容 HTTP 调用（CF Gateway / 原接口均适用），最多重试 3 次
-func (s *LLMService) callLLM(baseURL, model, token, prompt string) (string, error) {
-	s.semaphore <- struct{}{}
-	defer func() { <-s.semaphore }()
+// Configured 是否配置了对话模型（CF Gateway 或 LLM_TOKEN）
+func (s *LLMService) Configured() bool {
+	return s.provider != nil
+}
 
-	retryDelays := []time.Duration{3 * time.Second, 5 * time.Second, 8 * time.Second}
-	maxAttempts := 1 + len(retryDelays) // 首次 + 3次重试
+// Shutdown 取消所有进行中的 LLM 请求（服务器关闭时调用）
+func (s *LLMService) Shutdown() {
+	s.cancel()
+}
 
-	reqBody := ChatRequest{
-		Model: model,
-		Messages: []ChatMessage{
-			{Role: "user", Content: prompt},
-		},
+// withServiceContext 派生同时受调用方和服务关闭控制的 ctx
+func (s *LLMService) withServiceContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(s.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
 	}
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("marshal request failed: %v", err)
+// Complete 调用对话模型返回完整回复
+func (s *LLMService) Complete(ctx context.Context, req LLMRequest) (string, error) {
+	if s.provider == nil {
+		return "", ErrLLMNotConfigured
 	}
+	ctx, cancel := s.withServiceContext(ctx)
+	defer cancel()
+	return s.provider.Complete(ctx, req)
+}
 
-	apiURL := strings.TrimSuffix(baseURL, "/") + "/chat/completions"
-
-	var lastErr error
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		if attempt > 0 {
-			delay := retryDelays[attempt-1]
-			log.Printf("[LLM] 第 %d 次重试，等待 %v", attempt, delay)
-			time.Sleep(delay)
-		}
-
-		req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return "", fmt.Errorf("create request failed: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		resp, err := s.client.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("api request failed: %v", err)
-			log.Printf("[LLM] 请求失败 (attempt %d/%d): %v", attempt+1, maxAttempts, err)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			lastErr = fmt.Errorf("api returned non-200 status: %s", resp.Status)
-			log.Printf("[LLM] 非 200 状态码 (attempt %d/%d): %s", attempt+1, maxAttempts, resp.Status)
-			continue
-		}
-
-		var chatResp ChatResponse
-		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-			resp.Body.Close()
-			lastErr = fmt.Errorf("decode response failed: %v", err)
-			log.Printf("[LLM] 解析响应失败 (attempt %d/%d): %v", attempt+1, maxAttempts, err)
-			continue
-		}
-		resp.Body.Close()
-
-		if len(chatResp.Choices) > 0 {
-			return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
-		}
-
-		return "", nil
+// Stream 调用对话模型并逐段回调回复内容
+func (s *LLMService) Stream(ctx context.Context, req LLMRequest, onDelta func(delta string) error) (string, error) {
+	if s.provider == nil {
+		return "", ErrLLMNotConfigured
 	}
-
-	return "", fmt.Errorf("LLM 调用失败（已重试 %d 次）: %v", len(retryDelays), lastErr)
+	ctx, cancel := s.withServiceContext(ctx)
+	defer cancel()
+	return s.provider.Stream(ctx, req, onDelta)
 }
 
 // ==================== GenerateSummary ====================
 
-// GenerateSummary 通过 Cloudflare AI Gateway 生成摘要
-func (s *LLMService) GenerateSummary(title, content string) (string, error) {
-	gatewayURL := os.Getenv("CF_GATEWAY_URL")
-	gatewayModel := os.Getenv("CF_GATEWAY_MODEL")
-	gatewayToken := os.Getenv("CF_API_TOKEN")
-
-	if gatewayURL == "" || gatewayToken == "" {
-		return s.GenerateSummaryLegacy(title, content)
-	}
-	if gatewayModel == "" {
-		gatewayModel = s.config.Model
+// GenerateSummary 生成 RSS 文章的社区推荐摘要，同时返回所用的提示词版本；内容不适宜时返回 "CONTENT_UNSUITABLE"
+func (s *LLMService) GenerateSummary(ctx context.Context, title, content string) (string, int, error) {
+	if !s.Configured() {
+		return "未配置 LLM_TOKEN，请在 .env 文件中配置以使用真实 AI 功能。", 0, nil
 	}
 
-	prompt := buildSummaryPrompt(title, content)
-	resp, err := s.callLLM(gatewayURL, gatewayModel, gatewayToken, prompt)
+	prompt, err := RenderPrompt(PromptSummary, PromptVars{"Title": title, "Content": content})
 	if err != nil {
-		return "", err
-	}
-
-	if resp == "" || resp == "CONTENT_UNSUITABLE" {
-		return "CONTENT_UNSUITABLE", nil
-	}
-	return resp, nil
-}
-
-// GenerateSummaryLegacy 原始 LLM 接口（备用，切回时改名即可）
-func (s *LLMService) GenerateSummaryLegacy(title, content string) (string, error) {
-	if s.config.Token == "" {
-		return "未配置 LLM_TOKEN，请在 .env 文件中配置以使用真实 AI 功能。", nil
+		return "", 0, err
 	}
-
-	prompt := buildSummaryPrompt(title, content)
-	resp, err := s.callLLM(s.config.BaseURL, s.config.Model, s.config.Token, prompt)
+	resp, err := s.Complete(ctx, prompt.Request())
 	if err != nil {
-		return "", err
+		return "", prompt.Version, err
 	}
 
 	if resp == "" || resp == "CONTENT_UNSUITABLE" {
-		return "CONTENT_UNSUITABLE", nil
+		return "CONTENT_UNSUITABLE", prompt.Version, nil
 	}
-	return resp, nil
+	return resp, prompt.Version, nil
 }
 
-func buildSummaryPrompt(title, content string) string {
-	return fmt.Sprintf(`
-# Role
+// summarySystemPrompt 摘要生成的默认系统提示词（summary 提示词第 1 版）
+const summarySystemPrompt = `# Role
 你是一名深耕技术领域的【实战派开发者】，擅长将复杂的技术文档或新闻改写为逻辑清晰、极具实操价值的技术分享。你的文风：冷静、专业、直击痛点。你擅长将枯燥的技术文档或新闻，重构成一篇**有料、有趣、带点极客范儿**的社区分享帖。
 
 # Safety First (安全第一 - 优先级最高)
@@ -254,81 +216,58 @@ func buildSummaryPrompt(title, content string) string {
 ❌ AI风："本文深入分析了 OAuth 协议，它是一个非常重要的授权框架..."
 ✅ 你的风格："很多人觉得 OAuth 逻辑绕，其实它就是拿『临时令牌』换『长久令牌』的过程。今天把这套流程彻底捋一遍..."
 
-# Input Data
-### Title: %s
-### Content: %s
-
 # Absolute Reminder
 1. 必须返回简体中文，禁止任何形式的"废话总结"。
 2. 逻辑第一，代码第一，不要煽情，不要废话。
 3. 必须符合 Markdown 标准格式。
 4. 无法处理则返回 "CONTENT_UNSUITABLE"。
-`, title, content)
-}
+`
 
 // ==================== GenerateSEOMetadata ====================
 
 // SEOMetadata 包含生成的 SEO 元数据
 type SEOMetadata struct {
-	Keywords    string // 逗号分隔的关键词列表
-	Description string // 150 字以内的页面描述
+	Keywords      string // 逗号分隔的关键词列表
+	Description   string // 150 字以内的页面描述
+	AdReason      string // 判定为广告时模型给出的理由
+	PromptVersion int    // 所用的 seo 提示词版本
 }
 
-// GenerateSEOMetadata 通过 Cloudflare AI Gateway 生成 SEO 关键词和描述
-func (s *LLMService) GenerateSEOMetadata(title, content string) (*SEOMetadata, error) {
-	gatewayURL := os.Getenv("CF_GATEWAY_URL")
-	gatewayModel := os.Getenv("CF_GATEWAY_MODEL")
-	gatewayToken := os.Getenv("CF_API_TOKEN")
-
-	if gatewayURL == "" || gatewayToken == "" {
-		return s.GenerateSEOMetadataLegacy(title, content)
-	}
-	if gatewayModel == "" {
-		gatewayModel = s.config.Model
+// GenerateSEOMetadata 用启用中的 seo 提示词生成 SEO 关键词和描述（JSON 模式）；判定为广告时 Keywords 为 "AD"
+func (s *LLMService) GenerateSEOMetadata(ctx context.Context, title, content string) (*SEOMetadata, error) {
+	if !s.Configured() {
+		return nil, fmt.Errorf("LLM_TOKEN 未配置")
 	}
 
-	prompt := buildSEOPrompt(title, content)
-	resp, err := s.callLLM(gatewayURL, gatewayModel, gatewayToken, prompt)
+	prompt, err := RenderPrompt(PromptSEO, PromptVars{"Title": title, "Content": content})
 	if err != nil {
 		return nil, err
 	}
-
-	return parseSEOResponse(resp)
-}
-
-// GenerateSEOMetadataLegacy 原始 LLM 接口（备用）
-func (s *LLMService) GenerateSEOMetadataLegacy(title, content string) (*SEOMetadata, error) {
-	if s.config.Token == "" {
-		return nil, fmt.Errorf("LLM_TOKEN 未配置")
+	resp, err := s.Complete(ctx, prompt.Request())
+	if err != nil {
+		return nil, err
 	}
 
-	prompt := buildSEOPrompt(title, content)
-	resp, err := s.callLLM(s.config.BaseURL, s.config.Model, s.config.Token, prompt)
+	meta, err := parseSEOResponse(resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return parseSEOResponse(resp)
+	meta.PromptVersion = prompt.Version
+	return meta, nil
 }
 
-func buildSEOPrompt(title, content string) string {
-	contentForPrompt := content
-	if len([]rune(content)) > 500 {
-		contentForPrompt = string([]rune(content)[:500]) + "..."
-	}
-
-	return fmt.Sprintf(`
-# Role
+// seoSystemPrompt SEO 元数据生成的默认系统提示词（seo 提示词第 1 版）
+const seoSystemPrompt = `# Role
 你是一个专业的 SEO 优化专家，精通搜索引擎优化和内容营销。
 
 # Tasks
-1. 评估内容属性。当内容纯粹且明显属于【纯垃圾推广广告】（如：纯博彩引流、灰产拉群、纯 SEO 堆砌、无任何有效信息增量的商业硬广等）时，请**直接且仅**返回字符串 "AD"。
-   - **核心判定**：如果内容旨在"诱导点击/消费特定违规平台为唯一目的"，且无任何信息增量，判定为 "AD"；如果内容是"中立地报道行业动态（包括敏感行业平台等新闻）、技术解析或事件说明、正常的优质站点/平台推荐"，则**不属于**广告，应正常处理。
+1. 评估内容属性。当内容纯粹且明显属于【纯垃圾推广广告】（如：纯博彩引流、灰产拉群、纯 SEO 堆砌、无任何有效信息增量的商业硬广等）时，请**直接且仅**返回 {"ad":true,"reason":"一句话说明判定理由"}。
+   - **核心判定**：如果内容旨在"诱导点击/消费特定违规平台为唯一目的"，且无任何信息增量，判定为广告；如果内容是"中立地报道行业动态（包括敏感行业平台等新闻）、技术解析或事件说明、正常的优质站点/平台推荐"，则**不属于**广告，应正常处理。
    - **存疑从宽**：若内容有实质信息，包含行业新闻、平台动态、技术原理分析、客观事件描述、正常的优质站点推荐等信息价值，即使提及敏感平台，**一律不按广告处理**，正常生成关键词和描述。
 2. 如果是正常技术、新闻或社区讨论内容，基于其生成有利于 SEO 的关键词和页面描述。
 
 # Output Requirements
-- 如果判定为广告：仅返回 "AD"。
+- 如果判定为广告：仅返回 {"ad":true,"reason":"一句话说明判定理由"}，理由会展示给作者和管理员。
 - 如果判定为正常内容：请严格按照以下 JSON 格式返回，不要包含任何其他文字：
 {"keywords":"关键词1,关键词2,关键词3,...","description":"页面描述"}
 
@@ -343,19 +282,15 @@ func buildSEOPrompt(title, content string) string {
 3. 语言流畅自然，适合在搜索结果中展示
 4. 不要包含 emoji 或特殊符号
 
-# Input Data
-### Title: %s
-### Content: %s
-
 # Reminder
-- 如果判定为广告：仅返回 "AD"。
+- 如果判定为广告：仅返回 {"ad":true,"reason":"..."}。
 - 如果判定为正常内容：只返回 JSON，不要有任何其他文字。
-`, title, contentForPrompt)
-}
+`
 
 func parseSEOResponse(responseContent string) (*SEOMetadata, error) {
 	responseContent = strings.TrimSpace(responseContent)
 
+	// 兼容不支持 JSON 模式时的纯文本回复
 	if strings.ToUpper(responseContent) == "AD" {
 		return &SEOMetadata{Keywords: "AD"}, nil
 	}
@@ -368,70 +303,19 @@ func parseSEOResponse(responseContent string) (*SEOMetadata, error) {
 	}
 	jsonStr := responseContent[startIdx : endIdx+1]
 
-	var seoResult SEOMetadata
+	var seoResult struct {
+		AD          bool   `json:"ad"`
+		Reason      string `json:"reason"`
+		Keywords    string `json:"keywords"`
+		Description string `json:"description"`
+	}
 	if err := json.Unmarshal([]byte(jsonStr), &seoResult); err != nil {
 		log.Printf("[LLM-SEO] JSON 解析失败: %v, 原始内容: %s", err, jsonStr)
 		return nil, fmt.Errorf("parse SEO metadata failed: %v", err)
 	}
-
-	return &seoResult, nil
-}
-
-// ==================== GetEmbedding ====================
-
-// GetEmbedding 调用 Ollama 获取文本向量
-func (s *LLMService) GetEmbedding(text string) ([]float32, error) {
-	if s.config.OllamaBaseURL == "" {
-		return nil, fmt.Errorf("OLLAMA_BASE_URL 未配置")
-	}
-
-	type OllamaEmbeddingRequest struct {
-		Model  string `json:"model"`
-		Prompt string `json:"prompt"`
-	}
-
-	type OllamaEmbeddingResponse struct {
-		Embedding []float32 `json:"embedding"`
-	}
-
-	reqBody := OllamaEmbeddingRequest{
-		Model:  s.config.OllamaModel,
-		Prompt: text,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request failed: %v", err)
-	}
-
-	apiURL := strings.TrimSuffix(s.config.OllamaBaseURL, "/") + "/api/embeddings"
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("create request failed: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		log.Printf("[Ollama] API 请求失败: %v", err)
-		return nil, fmt.Errorf("ollama api request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[Ollama] API 返回非 200 状态码: %s", resp.Status)
-		return nil, fmt.Errorf("ollama api returned non-200 status: %s", resp.Status)
-	}
-
-	var embeddingResp OllamaEmbeddingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
-		return nil, fmt.Errorf("decode response failed: %v", err)
-	}
-
-	if len(embeddingResp.Embedding) == 0 {
-		return nil, fmt.Errorf("received empty embedding from ollama")
+	if seoResult.AD {
+		return &SEOMetadata{Keywords: "AD", AdReason: seoResult.Reason}, nil
 	}
 
-	return embeddingResp.Embedding, nil
+	return &SEOMetadata{Keywords: seoResult.Keywords, Description: seoResult.Description}, nil
 }
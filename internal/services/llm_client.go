@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LLM 调用参数
+const (
+	llmRequestTimeout  = 60 * time.Second // 非流式请求单次尝试的最长时间
+	llmMaxRetryAfter   = time.Minute      // 服务端 Retry-After 超过该值时不再等待
+	llmMaxErrorBodyLen = 512              // 错误日志中保留的响应体长度
+)
+
+// llmRetryDelays 默认重试间隔（首次 + 3 次重试）；429 带 Retry-After 时以服务端为准
+var llmRetryDelays = []time.Duration{3 * time.Second, 5 * time.Second, 8 * time.Second}
+
+// ErrLLMNotConfigured 未配置任何对话模型
+var ErrLLMNotConfigured = errors.New("LLM 未配置")
+
+// LLMSchema 结构化输出的 JSON Schema（response_format: json_schema）
+type LLMSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict,omitempty"`
+}
+
+// LLMRequest 一次对话补全请求
+type LLMRequest struct {
+	System    string        // 系统提示词，可为空
+	Messages  []ChatMessage // 对话消息（user / assistant）
+	JSON      bool          // JSON 模式（response_format: json_object）
+	Schema    *LLMSchema    // 结构化输出，设置后优先于 JSON 模式
+	MaxTokens int           // 最大输出 token 数，0 表示由服务端决定
+}
+
+// UserPrompt 只有一条用户消息的请求
+func UserPrompt(system, prompt string) LLMRequest {
+	return LLMRequest{
+		System:   system,
+		Messages: []ChatMessage{{Role: "user", Content: prompt}},
+	}
+}
+
+// LLMProvider 对话模型提供方。ctx 取消时立即中止请求和重试等待。
+type LLMProvider interface {
+	// Complete 返回完整回复
+	Complete(ctx context.Context, req LLMRequest) (string, error)
+	// Stream 逐段回调回复内容，onDelta 返回错误时中止；返回拼接后的完整回复
+	Stream(ctx context.Context, req LLMRequest, onDelta func(delta string) error) (string, error)
+}
+
+// llmHTTPError 非 200 响应
+type llmHTTPError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *llmHTTPError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("api returned non-200 status: %s: %s", e.Status, e.Body)
+	}
+	return "api returned non-200 status: " + e.Status
+}
+
+// retryable 429 和 5xx 可以重试，其余 4xx 是请求本身的问题
+func (e *llmHTTPError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// openAIChatProvider OpenAI 兼容的 /chat/completions（Cloudflare AI Gateway、Gemini 等均适用）
+type openAIChatProvider struct {
+	name      string // 日志中显示的名称
+	baseURL   string
+	model     string
+	token     string
+	client    *http.Client
+	semaphore chan struct{} // 同时进行的请求数上限，重试等待期间不占用
+}
+
+type chatCompletionRequest struct {
+	Model          string          `json:"model,omitempty"`
+	Messages       []ChatMessage   `json:"messages"`
+	Stream         bool            `json:"stream,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string     `json:"type"`
+	JSONSchema *LLMSchema `json:"json_schema,omitempty"`
+}
+
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+func (p *openAIChatProvider) Complete(ctx context.Context, req LLMRequest) (string, error) {
+	var result string
+	err := p.withRetry(ctx, req, false, func(resp *http.Response) error {
+		var chatResp ChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			return fmt.Errorf("decode response failed: %v", err)
+		}
+		if len(chatResp.Choices) > 0 {
+			result = strings.TrimSpace(chatResp.Choices[0].Message.Content)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (p *openAIChatProvider) Stream(ctx context.Context, req LLMRequest, onDelta func(delta string) error) (string, error) {
+	var full strings.Builder
+	err := p.withRetry(ctx, req, true, func(resp *http.Response) error {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				return nil
+			}
+			var chunk chatStreamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return fmt.Errorf("decode stream chunk failed: %v", err)
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			delta := chunk.Choices[0].Delta.Content
+			full.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+	return strings.TrimSpace(full.String()), err
+}
+
+// withRetry 发送请求并在网络错误、429 和 5xx 时重试；handle 处理 200 响应。
+// 流式响应一旦开始输出就不再重试，避免调用方收到重复内容。
+func (p *openAIChatProvider) withRetry(ctx context.Context, req LLMRequest, stream bool, handle func(*http.Response) error) error {
+	body := p.buildRequest(req, stream)
+	maxAttempts := 1 + len(llmRetryDelays)
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := llmRetryDelays[attempt-1]
+			var httpErr *llmHTTPError
+			if errors.As(lastErr, &httpErr) && httpErr.RetryAfter > 0 {
+				delay = httpErr.RetryAfter
+			}
+			log.Printf("[LLM] %s 第 %d 次重试，等待 %v", p.name, attempt, delay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		started, err := p.attempt(ctx, body, stream, handle)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr = err
+		log.Printf("[LLM] %s 请求失败 (attempt %d/%d): %v", p.name, attempt+1, maxAttempts, err)
+
+		var httpErr *llmHTTPError
+		if errors.As(err, &httpErr) {
+			// 不支持 response_format 的服务返回 400 时，去掉该参数再试一次
+			if httpErr.StatusCode == http.StatusBadRequest && body.ResponseFormat != nil {
+				log.Printf("[LLM] %s 可能不支持 response_format，改用普通输出", p.name)
+				body.ResponseFormat = nil
+				continue
+			}
+			if !httpErr.retryable() || httpErr.RetryAfter > llmMaxRetryAfter {
+				return err
+			}
+		}
+		if started {
+			return err
+		}
+	}
+
+	return fmt.Errorf("LLM 调用失败（已重试 %d 次）: %v", len(llmRetryDelays), lastErr)
+}
+
+// attempt 单次请求，只在请求期间占用并发名额；started 表示流式响应是否已开始输出
+func (p *openAIChatProvider) attempt(ctx context.Context, body chatCompletionRequest, stream bool, handle func(*http.Response) error) (started bool, err error) {
+	select {
+	case p.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	defer func() { <-p.semaphore }()
+
+	if !stream {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, llmRequestTimeout)
+		defer cancel()
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return false, fmt.Errorf("marshal request failed: %v", err)
+	}
+
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return false, fmt.Errorf("create request failed: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.token)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("api request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, llmMaxErrorBodyLen))
+		return false, &llmHTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Body:       strings.TrimSpace(string(errBody)),
+		}
+	}
+
+	return stream, handle(resp)
+}
+
+// buildRequest 把 LLMRequest 转为 OpenAI 请求体
+func (p *openAIChatProvider) buildRequest(req LLMRequest, stream bool) chatCompletionRequest {
+	messages := make([]ChatMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+
+	body := chatCompletionRequest{
+		Model:     p.model,
+		Messages:  messages,
+		Stream:    stream,
+		MaxTokens: req.MaxTokens,
+	}
+	switch {
+	case req.Schema != nil:
+		body.ResponseFormat = &responseFormat{Type: "json_schema", JSONSchema: req.Schema}
+	case req.JSON:
+		body.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	return body
+}
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期），无法解析时返回 0
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -42,28 +45,53 @@ func NewMailService() *MailService {
 	}
 }
 
+// mailJob 邮件发送任务的参数
+type mailJob struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// sendAsync 写入邮件发送任务，由任务队列发送并在失败时重试
 func (s *MailService) sendAsync(to []string, subject string, body string) {
 	if !s.Enabled {
 		return
 	}
 
-	go func() {
-		auth := smtp.PlainAuth("", s.Username, s.Password, s.Host)
-		addr := fmt.Sprintf("%s:%s", s.Host, s.Port)
+	EnqueueJob(JobSendMail, mailJob{To: to, Subject: subject, Body: body}, JobOptions{})
+}
+
+// send 通过 SMTP 发送邮件
+func (s *MailService) send(to []string, subject string, body string) error {
+	auth := smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	addr := fmt.Sprintf("%s:%s", s.Host, s.Port)
+
+	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+	msg := []byte(fmt.Sprintf("To: %s\r\n"+
+		"From: ZhuLink 通讯员 <%s>\r\n"+
+		"Subject: %s\r\n"+
+		"%s\r\n%s", strings.Join(to, ","), s.From, subject, mime, body))
 
-		mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-		msg := []byte(fmt.Sprintf("To: %s\r\n"+
-			"From: ZhuLink 通讯员 <%s>\r\n"+
-			"Subject: %s\r\n"+
-			"%s\r\n%s", strings.Join(to, ","), s.From, subject, mime, body))
+	return smtp.SendMail(addr, auth, s.From, to, msg)
+}
+
+// runSendMailJob 执行邮件发送任务
+func runSendMailJob(ctx context.Context, payload []byte) error {
+	var job mailJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
 
-		err := smtp.SendMail(addr, auth, s.From, to, msg)
-		if err != nil {
-			log.Printf("❌ Failed to send email to %v: %v", to, err)
-		} else {
-			log.Printf("✅ Email sent to %v: %s", to, subject)
-		}
-	}()
+	s := NewMailService()
+	if !s.Enabled {
+		return PermanentJobError(errors.New("MailService disabled: Missing SMTP environment variables"))
+	}
+	if err := s.send(job.To, job.Subject, job.Body); err != nil {
+		log.Printf("❌ Failed to send email to %v: %v", job.To, err)
+		return err
+	}
+	log.Printf("✅ Email sent to %v: %s", job.To, job.Subject)
+	return nil
 }
 
 func (s *MailService) parseTemplate(templateName string, data interface{}) (string, error) {
@@ -120,3 +148,24 @@ func (s *MailService) SendCommentNotification(email, activeUser, articleTitle, r
 	}
 	s.sendAsync([]string{email}, "💬 [新回响] "+activeUser+" 回复了你在《"+articleTitle+"》下的评论", body)
 }
+
+func (s *MailService) SendSavedSearchDigest(email, username string, groups []SavedSearchDigestGroup) {
+	total := 0
+	for _, g := range groups {
+		total += len(g.Entries)
+	}
+	if total == 0 {
+		return
+	}
+	data := map[string]interface{}{
+		"Username": username,
+		"Groups":   groups,
+		"Total":    total,
+	}
+	body, err := s.parseTemplate("saved_search_digest.html", data)
+	if err != nil {
+		log.Printf("Error rendering saved search digest email: %v", err)
+		return
+	}
+	s.sendAsync([]string{email}, fmt.Sprintf("🔔 [ZhuLink] 你保存的搜索有 %d 条新内容", total), body)
+}
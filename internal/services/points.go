@@ -12,37 +12,52 @@ import (
 
 // 积分动作常量
 const (
-	ActionPostCreate        = "发布帖子"
-	ActionPostLiked         = "帖子获赞"
-	ActionPostBookmarked    = "帖子被收藏"
-	ActionPostUnbookmark    = "帖子取消收藏"
-	ActionPostDownvoted     = "帖子被踩"
-	ActionPostDeleted       = "删除帖子"
-	ActionCommentCreate     = "发布评论"
-	ActionCommentLiked      = "评论获赞"
-	ActionCommentDownvoted  = "评论被踩"
-	ActionCommentDeleted    = "删除评论"
-	ActionDownvoteOther     = "踩了别人"
-	ActionCheckIn           = "每日签到"
-	ActionCheckInBonus      = "签到额外奖励"
-	ActionContentVioloation = "内容违规惩罚"
+	ActionPostCreate         = "发布帖子"
+	ActionPostLiked          = "帖子获赞"
+	ActionPostBookmarked     = "帖子被收藏"
+	ActionPostUnbookmark     = "帖子取消收藏"
+	ActionPostDownvoted      = "帖子被踩"
+	ActionPostDeleted        = "删除帖子"
+	ActionCommentCreate      = "发布评论"
+	ActionCommentLiked       = "评论获赞"
+	ActionCommentDownvoted   = "评论被踩"
+	ActionCommentDeleted     = "删除评论"
+	ActionDownvoteOther      = "踩了别人"
+	ActionPostUnliked        = "帖子被取消点赞"
+	ActionCommentUnliked     = "评论被取消点赞"
+	ActionPostUndownvoted    = "帖子被取消踩"
+	ActionCommentUndownvoted = "评论被取消踩"
+	ActionUndownvoteOther    = "取消踩别人"
+	ActionCheckIn            = "每日签到"
+	ActionCheckInBonus       = "签到额外奖励"
+	ActionContentVioloation  = "内容违规惩罚"
+	ActionFalseReport        = "举报不实"
+	ActionPostQuarantined    = "帖子被判定为广告"
+	ActionQuarantineRefund   = "申诉通过退还"
 )
 
 // 积分值常量
 const (
-	PointsPostCreate       = 1
-	PointsPostLiked        = 1
-	PointsPostBookmarked   = 3
-	PointsPostUnbookmark   = -3
-	PointsPostDownvoted    = -3
-	PointsPostDeleted      = -10
-	PointsCommentCreate    = 1
-	PointsCommentLiked     = 1
-	PointsCommentDownvoted = -3
-	PointsCommentDeleted   = -3
-	PointsDownvoteOther    = -1
-	PointsCheckIn          = 1
-	PointsContentViolation = -1
+	PointsPostCreate         = 1
+	PointsPostLiked          = 1
+	PointsPostBookmarked     = 3
+	PointsPostUnbookmark     = -3
+	PointsPostDownvoted      = -3
+	PointsPostDeleted        = -10
+	PointsCommentCreate      = 1
+	PointsCommentLiked       = 1
+	PointsCommentDownvoted   = -3
+	PointsCommentDeleted     = -3
+	PointsDownvoteOther      = -1
+	PointsPostUnliked        = -PointsPostLiked
+	PointsCommentUnliked     = -PointsCommentLiked
+	PointsPostUndownvoted    = -PointsPostDownvoted
+	PointsCommentUndownvoted = -PointsCommentDownvoted
+	PointsUndownvoteOther    = -PointsDownvoteOther
+	PointsCheckIn            = 1
+	PointsContentViolation   = -1
+	PointsFalseReport        = -2
+	PointsPostQuarantined    = -10
 )
 
 // 每日限制
@@ -55,26 +70,31 @@ const (
 // 传入用户ID、积分变动值（正数增加，负数扣除）、动作描述
 func AddPoints(userID uint, amount int, action string) error {
 	return db.DB.Transaction(func(tx *gorm.DB) error {
-		// 1. 创建积分明细记录
-		log := models.PointLog{
-			UserID: userID,
-			Amount: amount,
-			Action: action,
-		}
-		if err := tx.Create(&log).Error; err != nil {
-			return err
-		}
+		return addPointsTx(tx, userID, amount, action)
+	})
+}
 
-		// 2. 更新用户积分余额
-		if err := tx.Model(&models.User{}).
-			Where("id = ?", userID).
-			UpdateColumn("points", gorm.Expr("points + ?", amount)).
-			Error; err != nil {
-			return err
-		}
+// addPointsTx 在调用方的事务中添加积分并记录明细
+func addPointsTx(tx *gorm.DB, userID uint, amount int, action string) error {
+	// 1. 创建积分明细记录
+	log := models.PointLog{
+		UserID: userID,
+		Amount: amount,
+		Action: action,
+	}
+	if err := tx.Create(&log).Error; err != nil {
+		return err
+	}
 
-		return nil
-	})
+	// 2. 更新用户积分余额
+	if err := tx.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("points", gorm.Expr("points + ?", amount)).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // AddPointsAsync 异步添加积分（在 goroutine 中调用）
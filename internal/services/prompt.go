@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 提示词模板名称
+const (
+	PromptSummary           = "summary"            // RSS 转载推荐语
+	PromptSEO               = "seo"                // SEO 元数据与广告识别
+	PromptCommentModeration = "comment_moderation" // 评论审核
+	PromptThreadDigest      = "thread_digest"      // 评论区讨论要点
+)
+
+// PromptVariable 模板中可用的变量
+type PromptVariable struct {
+	Name        string
+	Description string
+}
+
+// PromptDefinition 一类提示词：用途、可用变量和内置默认内容
+type PromptDefinition struct {
+	Name          string
+	Title         string // 后台展示名称
+	Description   string
+	Variables     []PromptVariable
+	JSON          bool // 以 JSON 模式调用
+	DefaultSystem string
+	DefaultUser   string
+}
+
+// promptDefinitions 所有提示词，后台按此顺序展示
+var promptDefinitions = []PromptDefinition{
+	{
+		Name:        PromptSEO,
+		Title:       "SEO 元数据",
+		Description: "发帖和编辑后生成 SEO 关键词和页面描述，同时识别广告；返回 {\"ad\":true} 时隔离帖子并禁言作者，作者可申诉",
+		Variables: []PromptVariable{
+			{Name: "Title", Description: "帖子标题"},
+			{Name: "Content", Description: "帖子正文（Markdown）"},
+		},
+		JSON:          true,
+		DefaultSystem: seoSystemPrompt,
+		DefaultUser:   "# Input Data\n### Title: {{.Title}}\n### Content: {{truncate .Content 500}}",
+	},
+	{
+		Name:        PromptSummary,
+		Title:       "RSS 推荐语",
+		Description: "转载 RSS 文章且未填写推荐语时生成；返回 CONTENT_UNSUITABLE 时拒绝转载并扣除积分",
+		Variables: []PromptVariable{
+			{Name: "Title", Description: "文章标题"},
+			{Name: "Content", Description: "文章内容（RSS 描述，可能含 HTML）"},
+		},
+		DefaultSystem: summarySystemPrompt,
+		DefaultUser:   "# Input Data\n### Title: {{.Title}}\n### Content: {{.Content}}",
+	},
+	{
+		Name:        PromptCommentModeration,
+		Title:       "评论审核",
+		Description: "发表和编辑评论后判断是否为垃圾广告、辱骂或离题，按置信度和作者等级进入审核队列或自动折叠",
+		Variables: []PromptVariable{
+			{Name: "Title", Description: "帖子标题"},
+			{Name: "Content", Description: "帖子正文（Markdown）"},
+			{Name: "Comment", Description: "评论内容（不含回复引用）"},
+		},
+		JSON:          true,
+		DefaultSystem: commentModerationSystemPrompt,
+		DefaultUser:   "# Post\n### Title: {{.Title}}\n### Content: {{truncate .Content 300}}\n\n# Comment\n{{truncate .Comment 1000}}",
+	},
+	{
+		Name:        PromptThreadDigest,
+		Title:       "讨论要点",
+		Description: "评论较多的帖子在评论区顶部展示的讨论要点，以 #楼层 引用评论；评论数明显增长后重新生成",
+		Variables: []PromptVariable{
+			{Name: "Title", Description: "帖子标题"},
+			{Name: "Content", Description: "帖子正文（Markdown）"},
+			{Name: "Comments", Description: "评论列表，每条一行，格式为「#楼层 @用户名: 内容」"},
+		},
+		DefaultSystem: threadDigestSystemPrompt,
+		DefaultUser:   "# Post\n### Title: {{.Title}}\n### Content: {{truncate .Content 500}}\n\n# Comments\n{{.Comments}}",
+	},
+}
+
+// PromptVars 渲染模板的变量
+type PromptVars map[string]string
+
+// promptFuncs 模板中可用的函数
+var promptFuncs = template.FuncMap{
+	// truncate 按字符截断，截断时追加省略号
+	"truncate": func(s string, n int) string {
+		if len([]rune(s)) <= n {
+			return s
+		}
+		return truncateRunes(s, n) + "..."
+	},
+}
+
+// RenderedPrompt 渲染后的提示词及其版本
+type RenderedPrompt struct {
+	Name    string
+	Version int // 0 表示数据库不可用时使用的内置默认内容
+	System  string
+	User    string
+	JSON    bool
+}
+
+// Request 转为只有一条用户消息的 LLM 请求
+func (p RenderedPrompt) Request() LLMRequest {
+	req := UserPrompt(p.System, p.User)
+	req.JSON = p.JSON
+	return req
+}
+
+// PromptDefinitions 返回所有提示词定义
+func PromptDefinitions() []PromptDefinition {
+	return promptDefinitions
+}
+
+// GetPromptDefinition 按名称查找提示词定义
+func GetPromptDefinition(name string) (PromptDefinition, bool) {
+	for _, def := range promptDefinitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return PromptDefinition{}, false
+}
+
+// EnsureDefaultPrompts 为还没有任何版本的提示词写入内置默认内容作为第 1 版并启用
+func EnsureDefaultPrompts() {
+	for _, def := range promptDefinitions {
+		var count int64
+		if err := db.DB.Model(&models.PromptTemplate{}).Where("name = ?", def.Name).Count(&count).Error; err != nil || count > 0 {
+			continue
+		}
+		tpl := models.PromptTemplate{
+			Name:    def.Name,
+			Version: 1,
+			System:  def.DefaultSystem,
+			User:    def.DefaultUser,
+			Note:    "内置默认",
+			Active:  true,
+		}
+		if err := db.DB.Create(&tpl).Error; err != nil {
+			log.Printf("[Prompt] 写入默认提示词 %s 失败: %v", def.Name, err)
+		}
+	}
+}
+
+// ActivePromptTemplate 返回启用中的版本；数据库中没有（或未连接数据库的命令行工具）时使用内置默认内容（版本 0）
+func ActivePromptTemplate(name string) (models.PromptTemplate, error) {
+	def, ok := GetPromptDefinition(name)
+	if !ok {
+		return models.PromptTemplate{}, fmt.Errorf("未知的提示词 %q", name)
+	}
+	builtin := models.PromptTemplate{Name: name, System: def.DefaultSystem, User: def.DefaultUser}
+	if db.DB == nil {
+		return builtin, nil
+	}
+	var tpl models.PromptTemplate
+	if err := db.DB.Where("name = ? AND active = ?", name, true).First(&tpl).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[Prompt] 读取提示词 %s 失败，使用内置默认内容: %v", name, err)
+		}
+		return builtin, nil
+	}
+	return tpl, nil
+}
+
+// RenderPrompt 用启用中的版本渲染提示词
+func RenderPrompt(name string, vars PromptVars) (RenderedPrompt, error) {
+	tpl, err := ActivePromptTemplate(name)
+	if err != nil {
+		return RenderedPrompt{}, err
+	}
+	return RenderPromptTemplate(tpl, vars)
+}
+
+// RenderPromptTemplate 渲染指定版本（或未保存的草稿）；引用了不存在的变量时返回错误
+func RenderPromptTemplate(tpl models.PromptTemplate, vars PromptVars) (RenderedPrompt, error) {
+	def, ok := GetPromptDefinition(tpl.Name)
+	if !ok {
+		return RenderedPrompt{}, fmt.Errorf("未知的提示词 %q", tpl.Name)
+	}
+	system, err := executePromptText(tpl.Name+".system", tpl.System, vars)
+	if err != nil {
+		return RenderedPrompt{}, err
+	}
+	user, err := executePromptText(tpl.Name+".user", tpl.User, vars)
+	if err != nil {
+		return RenderedPrompt{}, err
+	}
+	return RenderedPrompt{Name: tpl.Name, Version: tpl.Version, System: system, User: user, JSON: def.JSON}, nil
+}
+
+func executePromptText(name, text string, vars PromptVars) (string, error) {
+	t, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("模板语法错误: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("模板渲染失败: %w", err)
+	}
+	return b.String(), nil
+}
+
+// ValidatePromptTemplate 用定义中的全部变量试渲染，检查语法和变量名
+func ValidatePromptTemplate(tpl models.PromptTemplate) error {
+	def, ok := GetPromptDefinition(tpl.Name)
+	if !ok {
+		return fmt.Errorf("未知的提示词 %q", tpl.Name)
+	}
+	if strings.TrimSpace(tpl.User) == "" {
+		return errors.New("用户消息模板不能为空")
+	}
+	vars := PromptVars{}
+	for _, v := range def.Variables {
+		vars[v.Name] = "示例"
+	}
+	_, err := RenderPromptTemplate(tpl, vars)
+	return err
+}
+
+// ListPromptVersions 按版本倒序列出提示词的所有版本
+func ListPromptVersions(name string) []models.PromptTemplate {
+	var versions []models.PromptTemplate
+	db.DB.Where("name = ?", name).Order("version DESC").Find(&versions)
+	return versions
+}
+
+// promptUsageColumn 记录提示词版本的表和字段
+type promptUsageColumn struct {
+	model  interface{}
+	column string
+}
+
+// promptUsageColumns 各提示词记录版本的位置
+var promptUsageColumns = map[string]promptUsageColumn{
+	PromptSEO:               {&models.Post{}, "seo_prompt_version"},
+	PromptSummary:           {&models.Post{}, "summary_prompt_version"},
+	PromptCommentModeration: {&models.CommentModeration{}, "prompt_version"}, // 只记录被标记的评论
+	PromptThreadDigest:      {&models.ThreadDigest{}, "prompt_version"},
+}
+
+// PromptVersionUsage 各版本的使用次数（生成过内容的帖子数，或标记过的评论数）
+func PromptVersionUsage(name string) map[int]int64 {
+	usage := make(map[int]int64)
+	usageColumn, ok := promptUsageColumns[name]
+	if !ok {
+		return usage
+	}
+	column := usageColumn.column
+	var rows []struct {
+		Version int
+		Count   int64
+	}
+	db.DB.Model(usageColumn.model).
+		Select(column + " AS version, COUNT(*) AS count").
+		Where(column + " > 0").
+		Group(column).
+		Scan(&rows)
+	for _, row := range rows {
+		usage[row.Version] = row.Count
+	}
+	return usage
+}
+
+// SavePromptVersion 保存为新版本（已有版本不可修改），activate 为 true 时同时启用
+func SavePromptVersion(name, system, user, note string, userID uint, activate bool) (*models.PromptTemplate, error) {
+	tpl := models.PromptTemplate{
+		Name:        name,
+		System:      system,
+		User:        user,
+		Note:        note,
+		CreatedByID: userID,
+	}
+	if err := ValidatePromptTemplate(tpl); err != nil {
+		return nil, err
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var maxVersion int
+		if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		tpl.Version = maxVersion + 1
+		if err := tx.Create(&tpl).Error; err != nil {
+			return err
+		}
+		if activate {
+			return activatePromptVersion(tx, name, tpl.Version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	tpl.Active = activate
+	return &tpl, nil
+}
+
+// ActivatePromptVersion 启用指定版本（用于回滚），同名的其他版本停用
+func ActivatePromptVersion(name string, version int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return activatePromptVersion(tx, name, version)
+	})
+}
+
+func activatePromptVersion(tx *gorm.DB, name string, version int) error {
+	var count int64
+	tx.Model(&models.PromptTemplate{}).Where("name = ? AND version = ?", name, version).Count(&count)
+	if count == 0 {
+		return fmt.Errorf("提示词 %s 不存在版本 %d", name, version)
+	}
+	if err := tx.Model(&models.PromptTemplate{}).Where("name = ? AND version <> ?", name, version).
+		Update("active", false).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PromptTemplate{}).Where("name = ? AND version = ?", name, version).
+		Update("active", true).Error
+}
+
+// PromptDryRun 试运行结果
+type PromptDryRun struct {
+	System string // 渲染后的系统提示词
+	User   string // 渲染后的用户消息
+	Output string // 模型原始输出
+	Parsed string // 按正式流程解析后的结果
+}
+
+// DryRunPrompt 用草稿模板和样例变量调用一次模型，不保存任何结果
+func (s *LLMService) DryRunPrompt(ctx context.Context, tpl models.PromptTemplate, vars PromptVars) (*PromptDryRun, error) {
+	rendered, err := RenderPromptTemplate(tpl, vars)
+	if err != nil {
+		return nil, err
+	}
+	result := &PromptDryRun{System: rendered.System, User: rendered.User}
+	result.Output, err = s.Complete(ctx, rendered.Request())
+	if err != nil {
+		return result, err
+	}
+
+	switch tpl.Name {
+	case PromptSEO:
+		meta, err := parseSEOResponse(result.Output)
+		switch {
+		case err != nil:
+			result.Parsed = "解析失败: " + err.Error()
+		case meta.Keywords == "AD":
+			result.Parsed = "判定为广告：帖子将被隔离，作者禁言 1 天并扣除积分（可申诉）\n理由: " + meta.AdReason
+		default:
+			result.Parsed = "关键词: " + meta.Keywords + "\n描述: " + meta.Description
+		}
+	case PromptSummary:
+		if result.Output == "" || result.Output == "CONTENT_UNSUITABLE" {
+			result.Parsed = "判定为不适宜：拒绝转载并扣除积分"
+		}
+	case PromptCommentModeration:
+		verdict, err := parseCommentModerationResponse(result.Output)
+		if err != nil {
+			result.Parsed = "解析失败: " + err.Error()
+		} else {
+			result.Parsed = fmt.Sprintf("类别: %s\n置信度: %.2f\n理由: %s", verdict.Category, verdict.Confidence, verdict.Reason)
+		}
+	}
+	return result, nil
+}
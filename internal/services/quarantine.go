@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuarantineMuteDuration 帖子被 AI 判定为广告后作者的禁言时长
+const QuarantineMuteDuration = 24 * time.Hour
+
+// 申诉相关错误
+var (
+	ErrQuarantineNotFound = errors.New("隔离记录不存在")
+	ErrAppealNotAllowed   = errors.New("当前状态无法申诉")
+	ErrAppealResolved     = errors.New("申诉已处理")
+)
+
+// QuarantinePost 隔离被 AI 判定为广告的帖子：帖子对其他人隐藏但保留，作者禁言 1 天并扣除积分，
+// 同时记录模型给出的理由，作者可在帖子页面申诉。已隔离或已确认违规的帖子不重复处罚，返回 nil。
+func QuarantinePost(postID uint, reason string, promptVersion int) (*models.PostQuarantine, error) {
+	var record *models.PostQuarantine
+	var post models.Post
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").First(&post, postID).Error; err != nil {
+			return err
+		}
+		if post.ModerationState == models.ModerationQuarantined || post.ModerationState == models.ModerationRemoved {
+			return nil
+		}
+		if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).
+			Update("moderation_state", models.ModerationQuarantined).Error; err != nil {
+			return err
+		}
+
+		// 禁言作者；已被封禁或禁言更久时保持原状，申诉通过时也不会解除
+		var mutedUntil *time.Time
+		expires := time.Now().Add(QuarantineMuteDuration)
+		author := post.User
+		if author.Status == 0 || (author.Status == 1 && author.PunishExpires != nil && author.PunishExpires.Before(expires)) {
+			if err := tx.Model(&models.User{}).Where("id = ?", author.ID).Updates(map[string]interface{}{
+				"status":         1,
+				"punish_expires": &expires,
+			}).Error; err != nil {
+				return err
+			}
+			mutedUntil = &expires
+		}
+
+		if err := addPointsTx(tx, author.ID, PointsPostQuarantined, ActionPostQuarantined); err != nil {
+			return err
+		}
+
+		record = &models.PostQuarantine{
+			PostID:        post.ID,
+			UserID:        author.ID,
+			Reason:        truncateRunes(reason, 500),
+			PromptVersion: promptVersion,
+			PenaltyPoints: -PointsPostQuarantined,
+			MutedUntil:    mutedUntil,
+			Status:        models.QuarantineActive,
+		}
+		return tx.Create(record).Error
+	})
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	log.Printf("[AntiSpam] 隔离广告贴: postID=%d, UserID=%d, Title=%s", post.ID, post.UserID, post.Title)
+
+	muteText := "账号已被禁言 1 天，"
+	if record.MutedUntil == nil {
+		muteText = ""
+	}
+	notify(post.UserID, fmt.Sprintf("系统检测到您发布的帖子 <a href=\"/p/%s\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a> 疑似广告，已被隔离（仅您和管理员可见），%s扣除 %d 积分。如有误判，可在帖子页面提交申诉。",
+		post.Pid, html.EscapeString(post.Title), muteText, record.PenaltyPoints))
+	notifyAdmins(fmt.Sprintf("AI 隔离了一条来自用户 @%s 的疑似广告贴 <a href=\"/p/%s\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a>，作者可申诉。",
+		html.EscapeString(post.User.Username), post.Pid, html.EscapeString(post.Title)))
+	return record, nil
+}
+
+// GetPostQuarantine 返回帖子的隔离记录，没有时返回 nil
+func GetPostQuarantine(postID uint) *models.PostQuarantine {
+	var record models.PostQuarantine
+	if err := db.DB.Where("post_id = ?", postID).First(&record).Error; err != nil {
+		return nil
+	}
+	return &record
+}
+
+// SubmitAppeal 作者对隔离的帖子提交申诉，每次隔离只能申诉一次
+func SubmitAppeal(postID uint, user *models.User, text string) error {
+	var record models.PostQuarantine
+	if err := db.DB.Preload("Post").Where("post_id = ?", postID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrQuarantineNotFound
+		}
+		return err
+	}
+	if record.UserID != user.ID {
+		return ErrQuarantineNotFound
+	}
+
+	now := time.Now()
+	res := db.DB.Model(&models.PostQuarantine{}).
+		Where("id = ? AND status = ?", record.ID, models.QuarantineActive).
+		Updates(map[string]interface{}{
+			"status":      models.QuarantineAppealed,
+			"appeal_text": truncateRunes(text, 1000),
+			"appealed_at": &now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAppealNotAllowed
+	}
+
+	notifyAdmins(fmt.Sprintf("用户 @%s 对被隔离的帖子 <a href=\"/p/%s\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a> 提交了申诉，请到 <a href=\"/admin/appeals\" class=\"text-moss font-medium hover:underline tracking-tight\">申诉处理</a> 查看。",
+		html.EscapeString(user.Username), record.Post.Pid, html.EscapeString(record.Post.Title)))
+	return nil
+}
+
+// RestoreQuarantinedPost 管理员通过申诉（也可在作者申诉前直接恢复）：恢复帖子显示并不再被 AI 隔离，
+// 解除本次自动禁言，退还扣除的积分。返回帖子 pid。
+func RestoreQuarantinedPost(id uint, adminID uint) (string, error) {
+	var record models.PostQuarantine
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Post").First(&record, id).Error; err != nil {
+			return err
+		}
+		if record.Status == models.QuarantineRestored || record.Status == models.QuarantineRejected {
+			return ErrAppealResolved
+		}
+
+		if err := tx.Model(&models.Post{}).Where("id = ?", record.PostID).Updates(map[string]interface{}{
+			"moderation_state": models.ModerationApproved,
+			"flag_score":       0,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 只解除本次隔离造成的禁言：禁言期被管理员延长过的不解除
+		if record.MutedUntil != nil {
+			if err := tx.Model(&models.User{}).
+				Where("id = ? AND status = ? AND punish_expires <= ?", record.UserID, 1, *record.MutedUntil).
+				Updates(map[string]interface{}{
+					"status":         0,
+					"punish_expires": nil,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		if record.PenaltyPoints > 0 {
+			if err := addPointsTx(tx, record.UserID, record.PenaltyPoints, ActionQuarantineRefund); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(&record).Updates(map[string]interface{}{
+			"status":         models.QuarantineRestored,
+			"resolved_by_id": adminID,
+			"resolved_at":    &now,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	notify(record.UserID, fmt.Sprintf("您的帖子 <a href=\"/p/%s\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a> 经管理员审核确认不是广告，已恢复显示，禁言已解除并退还 %d 积分。",
+		record.Post.Pid, html.EscapeString(record.Post.Title), record.PenaltyPoints))
+	return record.Post.Pid, nil
+}
+
+// RejectAppeal 管理员驳回申诉，帖子保持隔离。返回帖子 pid。
+func RejectAppeal(id uint, adminID uint) (string, error) {
+	var record models.PostQuarantine
+	if err := db.DB.Preload("Post").First(&record, id).Error; err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	res := db.DB.Model(&models.PostQuarantine{}).
+		Where("id = ? AND status IN ?", record.ID, []string{models.QuarantineActive, models.QuarantineAppealed}).
+		Updates(map[string]interface{}{
+			"status":         models.QuarantineRejected,
+			"resolved_by_id": adminID,
+			"resolved_at":    &now,
+		})
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		return "", ErrAppealResolved
+	}
+
+	notify(record.UserID, fmt.Sprintf("您对帖子 <a href=\"/p/%s\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a> 的申诉未通过，帖子保持隔离。",
+		record.Post.Pid, html.EscapeString(record.Post.Title)))
+	return record.Post.Pid, nil
+}
+
+// notify 发送一条系统通知
+func notify(userID uint, reason string) {
+	db.DB.Create(&models.Notification{
+		UserID: userID,
+		Type:   models.NotificationTypeSystem,
+		Reason: reason,
+	})
+}
+
+// notifyAdmins 向所有管理员发送系统通知
+func notifyAdmins(reason string) {
+	var adminIDs []uint
+	db.DB.Model(&models.User{}).Where("role = ?", "admin").Pluck("id", &adminIDs)
+	for _, id := range adminIDs {
+		notify(id, reason)
+	}
+}
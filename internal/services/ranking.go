@@ -4,18 +4,50 @@ import (
 	"context"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 	"zhulink/internal/db"
 	"zhulink/internal/models"
 	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 排名更新队列参数
+const (
+	rankingBatchSize     = 50              // 每批处理的帖子数
+	rankingPollInterval  = 2 * time.Second // 空闲时轮询队列的间隔（消化其他实例写入或重启前遗留的任务）
+	rankingHighWaterMark = 5000            // 队列深度超过该值时，ScheduleUpdate 阻塞等待消化
+	rankingLagWarning    = 5 * time.Minute // 最老任务等待超过该时长时打印告警
+	rankingDedupeWindow  = 5 * time.Second // 同一帖子在该时间内重复标记只写一次库
 )
 
-// RankingService 提供异步计算和更新帖子 Score 的服务
+// RankingService 提供异步计算和更新帖子各排名分数的服务
+// 待更新的帖子持久化在 ranking_queue_items 表中，进程崩溃或重启后由 worker 继续消化
 type RankingService struct {
-	queue   chan uint // 待更新的帖子 ID 队列
-	pending map[uint]bool
+	pending map[uint]time.Time // 本进程最近写入队列表的帖子及写入时间，用于去重，避免每次浏览都写库
+	unsaved map[uint]bool      // 等待 worker 写入队列表的帖子（浏览产生的标记，以及写库失败待重试的任务）
 	mu      sync.Mutex
+	wake    chan struct{} // 有新任务时唤醒 worker
 	done    chan struct{}
+	stopped chan struct{}  // worker 退出后关闭
+	rankers []utils.Ranker // 启用的排名算法，每个算法维护 posts 表中的一个分数列
+
+	depth         atomic.Int64  // 队列深度（worker 每批处理后从数据库校准）
+	drained       chan struct{} // 每处理完一批关闭并替换，用于唤醒被背压阻塞的调用方
+	processed     atomic.Uint64 // 累计处理的帖子数
+	lastProcessed atomic.Int64  // 最近一次处理完成的时间 (UnixNano)
+}
+
+// RankingQueueStats 排名更新队列的运行指标
+type RankingQueueStats struct {
+	Depth           int64     `json:"depth"`             // 待处理的帖子数
+	OldestEnqueued  time.Time `json:"oldest_enqueued"`   // 最老任务的入队时间
+	LagSeconds      float64   `json:"lag_seconds"`       // 最老任务已等待的秒数，即分数最多过期多久
+	Processed       uint64    `json:"processed"`         // 本进程启动以来处理的帖子数
+	LastProcessedAt time.Time `json:"last_processed_at"` // 最近一次处理完成的时间
+	Unsaved         int       `json:"unsaved"`           // 仍在内存中等待写入队列表的帖子数
 }
 
 var (
@@ -27,102 +59,244 @@ var (
 func GetRankingService() *RankingService {
 	once.Do(func() {
 		rankingService = &RankingService{
-			queue:   make(chan uint, 1000), // 缓冲队列，防止阻塞
-			pending: make(map[uint]bool),
+			pending: make(map[uint]time.Time),
+			unsaved: make(map[uint]bool),
+			wake:    make(chan struct{}, 1),
 			done:    make(chan struct{}),
+			stopped: make(chan struct{}),
+			drained: make(chan struct{}),
+			rankers: utils.LoadRankers(),
 		}
-		// 启动后台 worker
-		go rankingService.worker(rankingService.done)
+		// 启动后台 worker（上次未处理完的任务仍在队列表中，会被继续消化）
+		go rankingService.worker()
 	})
 	return rankingService
 }
 
-// ScheduleUpdate 将帖子加入更新队列（异步）
-// 使用去重机制避免短时间内重复计算同一帖子
+// Rankers 返回启用的排名算法（按列表展示顺序）
+func (s *RankingService) Rankers() []utils.Ranker {
+	return s.rankers
+}
+
+// Ranker 按名称查找排名算法
+func (s *RankingService) Ranker(name string) (utils.Ranker, bool) {
+	for _, r := range s.rankers {
+		if r.Name() == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// ScheduleUpdate 将帖子标记为待更新（写入持久化队列，由 worker 异步计算）
+// 同一帖子短时间内只写一次库；队列积压超过水位线时阻塞调用方，而不是丢弃任务
 func (s *RankingService) ScheduleUpdate(postID uint) {
 	s.mu.Lock()
-	if s.pending[postID] {
-		// 已在队列中，跳过
+	if at, ok := s.pending[postID]; ok && time.Since(at) < rankingDedupeWindow {
+		// 刚写入过队列，跳过
 		s.mu.Unlock()
 		return
 	}
-	s.pending[postID] = true
+	s.pending[postID] = time.Now()
 	s.mu.Unlock()
 
-	// 非阻塞发送到队列
+	s.waitForCapacity()
+
+	if err := enqueueRankingUpdate(postID); err != nil {
+		// 写库失败时暂存在内存，由 worker 重试
+		log.Printf("排名更新任务写入队列失败 (post=%d): %v", postID, err)
+		s.mu.Lock()
+		s.unsaved[postID] = true
+		s.mu.Unlock()
+	} else {
+		s.depth.Add(1)
+	}
+
 	select {
-	case s.queue <- postID:
-		// 成功加入队列
+	case s.wake <- struct{}{}:
 	default:
-		// 队列满了，移除 pending 标记
+	}
+}
+
+// ScheduleViewUpdate 浏览产生的更新标记：只记入内存，由 worker 批量写入队列表。
+// 不访问数据库也不受背压阻塞，保证队列积压时详情页仍能正常响应；背压只作用于投票、评论等写操作
+func (s *RankingService) ScheduleViewUpdate(postID uint) {
+	s.mu.Lock()
+	if at, ok := s.pending[postID]; ok && time.Since(at) < rankingDedupeWindow {
+		s.mu.Unlock()
+		return
+	}
+	s.pending[postID] = time.Now()
+	s.unsaved[postID] = true
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// waitForCapacity 队列深度超过水位线时等待 worker 消化（背压），服务停止后不再等待
+func (s *RankingService) waitForCapacity() {
+	for s.depth.Load() >= rankingHighWaterMark {
 		s.mu.Lock()
-		delete(s.pending, postID)
+		drained := s.drained
 		s.mu.Unlock()
-		log.Printf("排名更新队列已满，跳过帖子 %d", postID)
+		select {
+		case <-drained:
+		case <-s.stopped:
+			return
+		}
 	}
 }
 
-// worker 后台处理队列中的更新请求
-func (s *RankingService) worker(done chan struct{}) {
-	// 批量处理：收集一批请求后统一处理
-	batch := make([]uint, 0, 50)
-	ticker := time.NewTicker(500 * time.Millisecond) // 每 500ms 处理一批
+// enqueueRankingUpdate 写入队列表；已存在时只刷新 DirtyAt，保留首次入队时间
+func enqueueRankingUpdate(postID uint) error {
+	now := time.Now()
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"dirty_at"}),
+	}).Create(&models.RankingQueueItem{PostID: postID, EnqueuedAt: now, DirtyAt: now}).Error
+}
+
+// worker 后台消化持久化队列：有新任务时立即处理，否则定时轮询
+func (s *RankingService) worker() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(rankingPollInterval)
 	defer ticker.Stop()
+	lagTicker := time.NewTicker(time.Minute)
+	defer lagTicker.Stop()
 
 	for {
 		select {
-		case <-done:
-			// Flush final batch
-			if len(batch) > 0 {
-				s.processBatch(batch)
-			}
-			// Drain remaining in queue
-			for {
-				select {
-				case postID := <-s.queue:
-					batch = append(batch, postID)
-					if len(batch) >= 50 {
-						s.processBatch(batch)
-						batch = batch[:0]
-					}
-				default:
-					if len(batch) > 0 {
-						s.processBatch(batch)
-					}
-					log.Println("排名 worker 已成功刷新缓冲并停止")
-					return
-				}
-			}
-		case postID := <-s.queue:
-			batch = append(batch, postID)
-			// 如果达到批量大小，立即处理
-			if len(batch) >= 50 {
-				s.processBatch(batch)
-				batch = batch[:0]
+		case <-s.done:
+			// 未处理的任务留在队列表中，下次启动继续
+			s.persistUnsaved()
+			log.Println("排名 worker 已停止，剩余任务保留在队列中")
+			return
+		case <-lagTicker.C:
+			if stats := s.Stats(); stats.LagSeconds > rankingLagWarning.Seconds() {
+				log.Printf("排名更新队列积压：%d 个帖子待更新，最老任务已等待 %.0f 秒", stats.Depth, stats.LagSeconds)
 			}
+			continue
+		case <-s.wake:
 		case <-ticker.C:
-			// 定时处理剩余的
-			if len(batch) > 0 {
-				s.processBatch(batch)
-				batch = batch[:0]
-			}
 		}
+		s.persistUnsaved()
+		s.drain()
 	}
 }
 
-// processBatch 批量处理帖子 Score 更新
-func (s *RankingService) processBatch(postIDs []uint) {
-	for _, postID := range postIDs {
-		s.updatePostScore(postID)
+// drain 按入队顺序分批处理队列，直到队列为空或服务停止
+func (s *RankingService) drain() {
+	for {
+		var items []models.RankingQueueItem
+		if err := db.DB.Order("enqueued_at ASC").Limit(rankingBatchSize).Find(&items).Error; err != nil {
+			log.Printf("读取排名更新队列失败: %v", err)
+			return
+		}
+		if len(items) > 0 {
+			s.processBatch(items)
+		}
+		s.afterBatch(len(items) == 0)
 
-		// 清除 pending 状态
+		if len(items) < rankingBatchSize {
+			return
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
+
+// processBatch 批量处理帖子分数更新，处理完成后从队列表中移除
+func (s *RankingService) processBatch(items []models.RankingQueueItem) {
+	for _, item := range items {
+		// 先清除去重标记：处理期间再次变脏的帖子会刷新 DirtyAt，留在队列中等待下一轮
 		s.mu.Lock()
-		delete(s.pending, postID)
+		delete(s.pending, item.PostID)
 		s.mu.Unlock()
+
+		s.updatePostScore(item.PostID)
+
+		res := db.DB.Where("post_id = ? AND dirty_at = ?", item.PostID, item.DirtyAt).Delete(&models.RankingQueueItem{})
+		if res.Error == nil && res.RowsAffected == 0 {
+			// 处理期间又有新变化：从本次变化开始重新计算等待时间
+			db.DB.Model(&models.RankingQueueItem{}).Where("post_id = ?", item.PostID).
+				UpdateColumn("enqueued_at", gorm.Expr("dirty_at"))
+		}
+		s.processed.Add(1)
+	}
+	s.lastProcessed.Store(time.Now().UnixNano())
+}
+
+// afterBatch 校准队列深度、清理过期的去重标记，并唤醒被背压阻塞的调用方
+func (s *RankingService) afterBatch(empty bool) {
+	var depth int64
+	if empty {
+		s.depth.Store(0)
+	} else if err := db.DB.Model(&models.RankingQueueItem{}).Count(&depth).Error; err == nil {
+		s.depth.Store(depth)
+	}
+
+	s.mu.Lock()
+	for id, at := range s.pending {
+		if time.Since(at) >= rankingDedupeWindow {
+			delete(s.pending, id)
+		}
+	}
+	close(s.drained)
+	s.drained = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// persistUnsaved 重试写入之前写库失败的任务
+func (s *RankingService) persistUnsaved() {
+	s.mu.Lock()
+	ids := make([]uint, 0, len(s.unsaved))
+	for id := range s.unsaved {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		if err := enqueueRankingUpdate(id); err != nil {
+			return // 数据库仍不可用，下次再试
+		}
+		s.mu.Lock()
+		delete(s.unsaved, id)
+		s.mu.Unlock()
+	}
+}
+
+// Stats 返回排名更新队列的深度和延迟指标
+func (s *RankingService) Stats() RankingQueueStats {
+	stats := RankingQueueStats{Processed: s.processed.Load()}
+
+	var row struct {
+		Depth  int64
+		Oldest *time.Time
 	}
+	db.DB.Model(&models.RankingQueueItem{}).Select("COUNT(*) AS depth, MIN(enqueued_at) AS oldest").Scan(&row)
+	stats.Depth = row.Depth
+	if row.Oldest != nil {
+		stats.OldestEnqueued = *row.Oldest
+		stats.LagSeconds = time.Since(*row.Oldest).Seconds()
+	}
+	if ns := s.lastProcessed.Load(); ns > 0 {
+		stats.LastProcessedAt = time.Unix(0, ns)
+	}
+
+	s.mu.Lock()
+	stats.Unsaved = len(s.unsaved)
+	s.mu.Unlock()
+	return stats
 }
 
-// updatePostScore 计算并更新单个帖子的 Score
+// updatePostScore 用所有排名算法计算并更新单个帖子的分数
 func (s *RankingService) updatePostScore(postID uint) {
 	// 获取帖子信息
 	var post models.Post
@@ -131,13 +305,8 @@ func (s *RankingService) updatePostScore(postID uint) {
 		return
 	}
 
-	// 统计点赞数
-	var upvotes int64
-	db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = 1", postID).Count(&upvotes)
-
-	// 统计点踩数
-	var downvotes int64
-	db.DB.Model(&models.Vote{}).Where("post_id = ? AND value = -1", postID).Count(&downvotes)
+	// 统计加权赞/踩数（按投票者等级和账号年龄加权，不含被判定为刷票的投票）
+	upvotes, downvotes := weightedPostVotes(postID)
 
 	// 统计收藏数
 	var collects int64
@@ -147,25 +316,61 @@ func (s *RankingService) updatePostScore(postID uint) {
 	var comments int64
 	db.DB.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&comments)
 
-	// 计算新 Score
-	newScore := utils.CalculateScore(
-		post.CreatedAt,
-		int(upvotes),
-		int(downvotes),
-		int(collects),
-		post.Views,
-		int(comments),
-	)
+	input := utils.RankInput{
+		CreatedAt: post.CreatedAt,
+		Now:       time.Now(),
+		Upvotes:   upvotes,
+		Downvotes: downvotes,
+		Collects:  int(collects),
+		Views:     post.Views,
+		Comments:  int(comments),
+	}
 
-	// 更新数据库（Score 现在是 0-100 区间的整数）
-	scoreInt := int(newScore)
+	columns := make(map[string]interface{}, len(s.rankers))
+	for _, r := range s.rankers {
+		score := r.Score(input)
+		if r.Column() == "score" {
+			// score 列是整数（投票时会直接增量更新），保持原有的取整方式
+			columns["score"] = int(score)
+		} else {
+			columns[r.Column()] = score
+		}
+		if hot, ok := r.(utils.HotRanker); ok {
+			columns["engagement"] = hot.Engagement(input)
+		}
+	}
 
-	if err := db.DB.Model(&post).UpdateColumn("score", scoreInt).Error; err != nil {
+	if err := db.DB.Model(&post).UpdateColumns(columns).Error; err != nil {
 		log.Printf("更新帖子 %d Score 失败: %v", postID, err)
 	}
 }
 
-// Shutdown 停止 RankingService 的后台 worker
+// weightedPostVotes 汇总帖子的加权赞/踩数（界面仍显示原始票数）
+func weightedPostVotes(postID uint) (up, down float64) {
+	type voterRow struct {
+		Value     int
+		Points    int
+		CreatedAt time.Time
+	}
+	var rows []voterRow
+	db.DB.Table("votes").
+		Select("votes.value, users.points, users.created_at").
+		Joins("JOIN users ON users.id = votes.user_id").
+		Where("votes.post_id = ? AND votes.neutralized = ?", postID, false).
+		Scan(&rows)
+
+	for _, r := range rows {
+		weight := utils.VoteWeight(r.Points, r.CreatedAt)
+		if r.Value > 0 {
+			up += weight
+		} else {
+			down += weight
+		}
+	}
+	return up, down
+}
+
+// Shutdown 停止 RankingService 的后台 worker，等待当前批次处理完成
 func (s *RankingService) Shutdown() {
 	select {
 	case <-s.done:
@@ -173,6 +378,7 @@ func (s *RankingService) Shutdown() {
 	default:
 		close(s.done)
 	}
+	<-s.stopped
 }
 
 // UpdatePostScoreSync 同步更新帖子 Score（用于需要立即生效的场景）
@@ -186,6 +392,7 @@ func (s *RankingService) StartScheduledScoreUpdate(ctx context.Context) {
 		// 启动时先执行一次
 		log.Println("启动时执行首次文章分数更新...")
 		s.updateHotPosts()
+		s.backfillRankScores()
 		log.Println("首次文章分数更新完成")
 
 		// 每小时执行一次
@@ -232,3 +439,22 @@ func (s *RankingService) updateHotPosts() {
 
 	log.Printf("本次更新 %d 篇文章分数", count)
 }
+
+// backfillRankScores 为新增排名列尚未计算过的历史帖子补算分数
+// 有未剔除的点赞却没有最佳分数、有互动却没有互动值，说明帖子还没被新算法处理过
+func (s *RankingService) backfillRankScores() {
+	var ids []uint
+	db.DB.Model(&models.Post{}).
+		Where("best_score = 0 AND EXISTS (SELECT 1 FROM votes WHERE votes.post_id = posts.id AND votes.value > 0 AND votes.neutralized = ?)", false).
+		Or(`engagement = 0 AND (views > 0
+			OR EXISTS (SELECT 1 FROM votes WHERE votes.post_id = posts.id AND votes.neutralized = ?)
+			OR EXISTS (SELECT 1 FROM comments WHERE comments.post_id = posts.id)
+			OR EXISTS (SELECT 1 FROM bookmarks WHERE bookmarks.post_id = posts.id))`, false).
+		Pluck("id", &ids)
+	for _, id := range ids {
+		s.updatePostScore(id)
+	}
+	if len(ids) > 0 {
+		log.Printf("补算 %d 篇历史文章的排名分数", len(ids))
+	}
+}
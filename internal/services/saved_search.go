@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/searchquery"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxSavedSearchesPerUser 每个用户最多保存的搜索数量
+const MaxSavedSearchesPerUser = 20
+
+// savedSearchDigestHour 每日邮件摘要的发送时间（点）
+const savedSearchDigestHour = 8
+
+var (
+	ErrSavedSearchEmpty = errors.New("搜索条件为空，无法保存")
+	ErrSavedSearchLimit = fmt.Errorf("最多只能保存 %d 个搜索", MaxSavedSearchesPerUser)
+)
+
+// CreateSavedSearch 保存搜索（排序方式不参与匹配，不保存）；相同条件已保存时返回已有记录
+func CreateSavedSearch(userID uint, q searchquery.Query, emailDigest bool) (*models.SavedSearch, error) {
+	if q.IsEmpty() {
+		return nil, ErrSavedSearchEmpty
+	}
+	q.Sort = ""
+	queryString := q.String()
+
+	var existing models.SavedSearch
+	if err := db.DB.Where("user_id = ? AND query = ?", userID, queryString).First(&existing).Error; err == nil {
+		return &existing, nil
+	}
+
+	var count int64
+	db.DB.Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&count)
+	if count >= MaxSavedSearchesPerUser {
+		return nil, ErrSavedSearchLimit
+	}
+
+	saved := models.SavedSearch{
+		UserID:      userID,
+		Query:       queryString,
+		Node:        q.Node,
+		Author:      strings.ToLower(q.Author),
+		Site:        q.Site,
+		Type:        q.Type,
+		EmailDigest: emailDigest,
+	}
+	if err := db.DB.Create(&saved).Error; err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// MatchSavedSearchesForPost 新帖发布后匹配所有保存的搜索（作者自己的除外），命中则发送提醒
+func MatchSavedSearchesForPost(postID uint) {
+	var post models.Post
+	if err := db.DB.Preload("User").Preload("Node").First(&post, postID).Error; err != nil {
+		return
+	}
+	if !models.IsPubliclyListed(post.ModerationState) {
+		return
+	}
+
+	doc := searchquery.Document{
+		Title:     post.Title,
+		Body:      post.Content,
+		Node:      post.Node.Name,
+		Author:    post.User.Username,
+		URL:       post.URL,
+		CreatedAt: post.CreatedAt,
+	}
+	matchSavedSearches(doc, post.UserID, func(saved models.SavedSearch) {
+		reason := fmt.Sprintf("您保存的搜索「%s」有新帖子：<a href=\"/p/%s\" target=\"_blank\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a>",
+			html.EscapeString(saved.Query), post.Pid, html.EscapeString(post.Title))
+		recordSavedSearchMatch(saved, post.ID, 0, reason)
+	})
+}
+
+// MatchSavedSearchesForComment 新评论发布后匹配所有保存的搜索（评论者自己的除外）。
+// 只匹配评论正文，节点、类型、来源网站等过滤器取自所在帖子。
+func MatchSavedSearchesForComment(commentID uint) {
+	var comment models.Comment
+	if err := db.DB.Preload("User").Preload("Post.Node").First(&comment, commentID).Error; err != nil {
+		return
+	}
+	if IsCommentDeleted(&comment) || !models.IsPubliclyListed(comment.ModerationState) || !models.IsPubliclyListed(comment.Post.ModerationState) {
+		return
+	}
+
+	// 系统拼接的回复引用含被回复者用户名，不参与匹配
+	_, body := SplitReplyPrefix(comment.Content)
+	doc := searchquery.Document{
+		Body:      body,
+		Node:      comment.Post.Node.Name,
+		Author:    comment.User.Username,
+		URL:       comment.Post.URL,
+		CreatedAt: comment.CreatedAt,
+	}
+	matchSavedSearches(doc, comment.UserID, func(saved models.SavedSearch) {
+		reason := fmt.Sprintf("您保存的搜索「%s」在 <a href=\"/p/%s#comment-%d\" target=\"_blank\" class=\"text-moss font-medium hover:underline tracking-tight\">《%s》</a> 中有新评论",
+			html.EscapeString(saved.Query), comment.Post.Pid, comment.ID, html.EscapeString(comment.Post.Title))
+		recordSavedSearchMatch(saved, comment.PostID, comment.ID, reason)
+	})
+}
+
+// matchSavedSearches 先按节点、作者、网站和类型过滤器在 SQL 中筛出候选，再逐个解析与新内容匹配，
+// 跳过内容作者自己的搜索
+func matchSavedSearches(doc searchquery.Document, authorID uint, onMatch func(models.SavedSearch)) {
+	query := db.DB.Where("user_id <> ?", authorID).
+		Where("node = '' OR node = ?", doc.Node).
+		Where("author = '' OR author = ?", strings.ToLower(doc.Author))
+	if sites := searchquery.SiteCandidates(doc.URL); len(sites) > 0 {
+		query = query.Where("type <> ?", searchquery.TypeAsk).Where("site = '' OR site IN ?", sites)
+	} else {
+		query = query.Where("type <> ? AND site = ''", searchquery.TypeLink)
+	}
+
+	var searches []models.SavedSearch
+	if err := query.Find(&searches).Error; err != nil {
+		log.Printf("[SavedSearch] 加载保存的搜索失败: %v", err)
+		return
+	}
+	for _, saved := range searches {
+		if searchquery.Parse(saved.Query).Match(doc) {
+			onMatch(saved)
+		}
+	}
+}
+
+// recordSavedSearchMatch 记录匹配并发送站内通知；同一内容只提醒一次
+func recordSavedSearchMatch(saved models.SavedSearch, postID, commentID uint, reason string) {
+	match := models.SavedSearchMatch{
+		SavedSearchID: saved.ID,
+		UserID:        saved.UserID,
+		PostID:        postID,
+		CommentID:     commentID,
+		DigestPending: saved.EmailDigest,
+	}
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&match)
+	if result.Error != nil {
+		log.Printf("[SavedSearch] 记录匹配失败 (search=%d, post=%d, comment=%d): %v", saved.ID, postID, commentID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	now := time.Now()
+	db.DB.Model(&models.SavedSearch{}).Where("id = ?", saved.ID).Updates(map[string]interface{}{
+		"match_count":     gorm.Expr("match_count + 1"),
+		"last_matched_at": now,
+	})
+
+	db.DB.Create(&models.Notification{
+		UserID: saved.UserID,
+		Type:   models.NotificationTypeSavedSearch,
+		Reason: reason,
+	})
+}
+
+// SavedSearchDigestEntry 邮件摘要中的一条匹配
+type SavedSearchDigestEntry struct {
+	Title     string
+	Link      string
+	IsComment bool
+}
+
+// SavedSearchDigestGroup 邮件摘要中一个保存的搜索及其匹配
+type SavedSearchDigestGroup struct {
+	Query      string
+	SearchLink string
+	Entries    []SavedSearchDigestEntry
+}
+
+// StartSavedSearchDigest 启动每日邮件摘要任务（每天早上 8 点）。
+// 到点时写入以日期为去重键的后台任务，多个实例同时到点也只会发送一次
+func StartSavedSearchDigest(ctx context.Context) {
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), savedSearchDigestHour, 0, 0, 0, now.Location())
+			if now.After(next) {
+				next = next.Add(24 * time.Hour)
+			}
+
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Println("保存的搜索邮件摘要任务已停止")
+				return
+			case <-timer.C:
+			}
+
+			EnqueueJob(JobSavedSearchDigest, struct{}{}, JobOptions{
+				DedupeKey: JobSavedSearchDigest + ":" + next.Format("2006-01-02"),
+			})
+		}
+	}()
+}
+
+// runSavedSearchDigestJob 执行每日邮件摘要任务
+func runSavedSearchDigestJob(ctx context.Context, payload []byte) error {
+	return SendSavedSearchDigests()
+}
+
+// SendSavedSearchDigests 把等待中的匹配按用户汇总，每人发送一封邮件摘要。
+// 匹配先在一条 UPDATE 中被认领（清除 digest_pending），多个实例同时执行时每条匹配只会发送一次
+func SendSavedSearchDigests() error {
+	var claimed []uint
+	if err := db.DB.Raw(`UPDATE saved_search_matches SET digest_pending = false
+		WHERE digest_pending = true RETURNING id`).Scan(&claimed).Error; err != nil {
+		return err
+	}
+	if len(claimed) == 0 {
+		return nil
+	}
+
+	var matches []models.SavedSearchMatch
+	err := db.DB.Preload("SavedSearch").Preload("Post").
+		Where("id IN ?", claimed).
+		Order("user_id ASC, saved_search_id ASC, created_at ASC").
+		Find(&matches).Error
+	if err != nil {
+		return err
+	}
+
+	siteURL := os.Getenv("SITE_URL")
+	if siteURL == "" {
+		siteURL = "https://zhulink.vip"
+	}
+	mail := NewMailService()
+
+	// 按用户、保存的搜索分组
+	byUser := make(map[uint][]SavedSearchDigestGroup)
+	var userIDs []uint
+	for _, m := range matches {
+		// 期间关闭了邮件摘要或帖子已被隐藏的匹配不再发送
+		if !m.SavedSearch.EmailDigest || m.Post.ID == 0 || !models.IsPubliclyListed(m.Post.ModerationState) {
+			continue
+		}
+
+		groups, seen := byUser[m.UserID]
+		if !seen {
+			userIDs = append(userIDs, m.UserID)
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Query != m.SavedSearch.Query {
+			q := searchquery.Parse(m.SavedSearch.Query)
+			groups = append(groups, SavedSearchDigestGroup{
+				Query:      m.SavedSearch.Query,
+				SearchLink: siteURL + "/search?" + q.Values().Encode(),
+			})
+		}
+		entry := SavedSearchDigestEntry{
+			Title:     m.Post.Title,
+			Link:      fmt.Sprintf("%s/p/%s", siteURL, url.PathEscape(m.Post.Pid)),
+			IsComment: m.CommentID != 0,
+		}
+		if entry.IsComment {
+			entry.Link = fmt.Sprintf("%s#comment-%d", entry.Link, m.CommentID)
+		}
+		groups[len(groups)-1].Entries = append(groups[len(groups)-1].Entries, entry)
+		byUser[m.UserID] = groups
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		db.DB.Where("id IN ?", userIDs).Find(&users)
+	}
+	for _, user := range users {
+		if user.Email == "" {
+			continue
+		}
+		mail.SendSavedSearchDigest(user.Email, user.Username, byUser[user.ID])
+	}
+
+	log.Printf("保存的搜索邮件摘要：%d 位用户，%d 条匹配", len(users), len(matches))
+	return nil
+}
+
+// ListSavedSearches 用户保存的搜索，最新的在前
+func ListSavedSearches(userID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := db.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&searches).Error
+	return searches, err
+}
+
+// DeleteSavedSearch 删除用户自己的保存的搜索（匹配记录随外键级联删除）
+func DeleteSavedSearch(userID, id uint) error {
+	return db.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SavedSearch{}).Error
+}
+
+// SetSavedSearchDigest 开启或关闭每日邮件摘要
+func SetSavedSearchDigest(userID, id uint, enabled bool) error {
+	return db.DB.Model(&models.SavedSearch{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("email_digest", enabled).Error
+}
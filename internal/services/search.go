@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"html/template"
+	"log"
+	"regexp"
+	"strings"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/searchquery"
+	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 全文索引参数
+const (
+	searchIndexContentRunes = 20000 // 正文和评论各自参与索引的最大字符数，防止 tsvector 超限
+	searchSnippetRunes      = 160   // 搜索结果摘要长度
+)
+
+// searchVectorSQL 按权重组合各字段的检索词元：标题 A、关键词/描述 B、正文 C、评论 D
+const searchVectorSQL = `setweight(to_tsvector('simple', ?), 'A') ||
+	setweight(to_tsvector('simple', ?), 'B') ||
+	setweight(to_tsvector('simple', ?), 'C') ||
+	setweight(to_tsvector('simple', ?), 'D')`
+
+// searchRankSQL 相关度 × 时效加成：新帖最多加成一倍，约一个月后加成减半
+const searchRankSQL = `ts_rank_cd(posts.search_vector, plainto_tsquery('simple', ?), 32) *
+	(1 + 1.0 / (1 + EXTRACT(EPOCH FROM (NOW() - posts.created_at)) / 2592000))`
+
+// SearchResult 一条搜索结果
+type SearchResult struct {
+	models.Post
+	TitleHTML template.HTML // 高亮后的标题
+	Snippet   template.HTML // 高亮后的正文摘要
+}
+
+// IndexPost 重建帖子的全文索引（标题、SEO 关键词和描述、正文、未删除且正常显示的评论）
+func IndexPost(postID uint) error {
+	var post models.Post
+	if err := db.DB.Select("id, title, content, seo_keywords, seo_description").First(&post, postID).Error; err != nil {
+		return err
+	}
+
+	var comments []string
+	db.DB.Model(&models.Comment{}).
+		Where("post_id = ? AND content NOT IN ? AND moderation_state NOT IN ?", postID,
+			[]string{CommentDeletedContent, CommentAdminDeletedContent},
+			[]string{models.ModerationHidden, models.ModerationRemoved}).
+		Order("created_at ASC").
+		Pluck("content", &comments)
+
+	return db.DB.Exec("UPDATE posts SET search_vector = "+searchVectorSQL+" WHERE id = ?",
+		utils.SearchDocumentTokens(post.Title),
+		utils.SearchDocumentTokens(post.SEOKeywords+" "+post.SEODescription),
+		utils.SearchDocumentTokens(truncateRunes(post.Content, searchIndexContentRunes)),
+		utils.SearchDocumentTokens(truncateRunes(strings.Join(comments, "\n"), searchIndexContentRunes)),
+		postID,
+	).Error
+}
+
+// IndexPostAsync 异步重建帖子的全文索引（发帖、编辑、评论变化后调用）
+func IndexPostAsync(postID uint) {
+	go func() {
+		if err := IndexPost(postID); err != nil {
+			log.Printf("更新帖子 %d 全文索引失败: %v", postID, err)
+		}
+	}()
+}
+
+// indexCommentPostAsync 评论被折叠、恢复或确认违规后，异步重建其所在帖子的全文索引
+func indexCommentPostAsync(commentID uint) {
+	go func() {
+		var postID uint
+		if err := db.DB.Model(&models.Comment{}).Select("post_id").Where("id = ?", commentID).Scan(&postID).Error; err != nil || postID == 0 {
+			return
+		}
+		if err := IndexPost(postID); err != nil {
+			log.Printf("更新帖子 %d 全文索引失败: %v", postID, err)
+		}
+	}()
+}
+
+// StartSearchIndexBackfill 后台为尚未建立全文索引的历史帖子补建索引
+func StartSearchIndexBackfill(ctx context.Context) {
+	go func() {
+		var ids []uint
+		db.DB.Model(&models.Post{}).Where("search_vector IS NULL").Order("id DESC").Pluck("id", &ids)
+		if len(ids) == 0 {
+			return
+		}
+		log.Printf("开始为 %d 篇帖子补建全文索引...", len(ids))
+		for i, id := range ids {
+			select {
+			case <-ctx.Done():
+				log.Printf("全文索引补建中断，已完成 %d/%d", i, len(ids))
+				return
+			default:
+			}
+			if err := IndexPost(id); err != nil {
+				log.Printf("更新帖子 %d 全文索引失败: %v", id, err)
+			}
+		}
+		log.Printf("全文索引补建完成，共 %d 篇", len(ids))
+	}()
+}
+
+// netVotesSQL 帖子的净赞数（不含被剔除的刷票投票），用于 score: 过滤和按得分排序
+const netVotesSQL = `(SELECT COALESCE(SUM(v.value), 0) FROM votes v WHERE v.post_id = posts.id AND v.neutralized = false)`
+
+// applySearchFilters 应用结构化过滤器、精确短语和排除词（已排除隐藏和移除的帖子）
+func applySearchFilters(tx *gorm.DB, q searchquery.Query) *gorm.DB {
+	tx = tx.Scopes(models.PubliclyListed)
+
+	if q.Node != "" {
+		tx = tx.Where("posts.node_id IN (SELECT id FROM nodes WHERE name = ?)", q.Node)
+	}
+	if q.Author != "" {
+		tx = tx.Where("posts.user_id IN (SELECT id FROM users WHERE LOWER(username) = LOWER(?))", q.Author)
+	}
+	if q.Site != "" {
+		// 匹配该域名及其子域名
+		tx = tx.Where("posts.url ~* ?", `^[a-z][a-z0-9+.-]*://([^/]*\.)?`+regexp.QuoteMeta(q.Site)+`([/:?#]|$)`)
+	}
+	switch q.Type {
+	case searchquery.TypeAsk:
+		tx = tx.Where("COALESCE(posts.url, '') = ''")
+	case searchquery.TypeLink:
+		tx = tx.Where("COALESCE(posts.url, '') <> ''")
+	}
+	if q.After != nil {
+		tx = tx.Where("posts.created_at >= ?", *q.After)
+	}
+	if q.Before != nil {
+		tx = tx.Where("posts.created_at < ?", *q.Before)
+	}
+	if q.Score != nil {
+		switch q.Score.Op {
+		case ">", ">=", "<", "<=", "=":
+			tx = tx.Where(netVotesSQL+" "+q.Score.Op+" ?", q.Score.Value)
+		}
+	}
+
+	// 精确短语必须原样出现在标题或正文中（分词检索无法保证词序）
+	for _, phrase := range q.Phrases {
+		pattern := "%" + utils.EscapeLike(phrase) + "%"
+		tx = tx.Where("(posts.title ILIKE ? OR posts.content ILIKE ?)", pattern, pattern)
+	}
+	for _, excluded := range q.Excluded {
+		if tokens := utils.SearchQueryTokens(excluded); tokens != "" {
+			tx = tx.Where("NOT COALESCE(posts.search_vector @@ plainto_tsquery('simple', ?), false)", tokens)
+		}
+	}
+	return tx
+}
+
+// keywordSearchQuery 关键词检索的基础查询，可复用于计数和取数；没有检索词时只按过滤器筛选
+func keywordSearchQuery(q searchquery.Query) *gorm.DB {
+	tx := applySearchFilters(db.DB.Model(&models.Post{}), q)
+	if tokens := utils.SearchQueryTokens(q.Keywords()); tokens != "" {
+		tx = tx.Where("posts.search_vector @@ plainto_tsquery('simple', ?)", tokens)
+	}
+	return tx.Session(&gorm.Session{})
+}
+
+// searchOrder 搜索结果排序：相关度（含时效加成，没有检索词时按时间）、最新或得分最高
+func searchOrder(q searchquery.Query) clause.Expr {
+	switch q.SortOrDefault() {
+	case searchquery.SortNew:
+		return gorm.Expr("posts.created_at DESC")
+	case searchquery.SortTop:
+		return gorm.Expr(netVotesSQL + " DESC, posts.created_at DESC")
+	}
+	if tokens := utils.SearchQueryTokens(q.Keywords()); tokens != "" {
+		return gorm.Expr(searchRankSQL+" DESC, posts.created_at DESC", tokens)
+	}
+	return gorm.Expr("posts.created_at DESC")
+}
+
+// SearchPosts 按关键词和过滤器检索帖子，返回当前页帖子和总数
+func SearchPosts(q searchquery.Query, page, perPage int) ([]models.Post, int64, error) {
+	if q.IsEmpty() {
+		return nil, 0, nil
+	}
+
+	base := keywordSearchQuery(q)
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := base.Preload("User").Preload("Node").
+		Order(searchOrder(q)).
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&posts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return posts, total, nil
+}
+
+// HighlightSearchResults 为搜索结果生成高亮标题和摘要，keywords 为查询中的检索词
+func HighlightSearchResults(posts []models.Post, keywords string) []SearchResult {
+	terms := utils.SearchTerms(keywords)
+	results := make([]SearchResult, len(posts))
+	for i, p := range posts {
+		snippetSource := p.Content
+		if strings.TrimSpace(snippetSource) == "" {
+			snippetSource = p.SEODescription
+		}
+		results[i] = SearchResult{
+			Post:      p,
+			TitleHTML: utils.HighlightSnippet(p.Title, terms, len([]rune(p.Title))),
+			Snippet:   utils.HighlightSnippet(snippetSource, terms, searchSnippetRunes),
+		}
+	}
+	return results
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/searchquery"
+	"zhulink/internal/utils"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+)
+
+// 混合检索参数
+const (
+	hybridCandidates      = 100             // 关键词和向量各取的候选数量
+	hybridRRFK            = 60              // 倒数排名融合 (RRF) 的平滑常数
+	semanticMinSimilarity = 0.5             // 向量候选的最低余弦相似度，过滤无关结果
+	semanticEmbedTimeout  = 3 * time.Second // 查询向量化的最长等待时间，超时即退回关键词检索
+	semanticEmbedCacheTTL = time.Hour       // 查询向量缓存时间
+	semanticRetryAfter    = time.Minute     // 向量服务失败后暂停语义检索的时间
+)
+
+// semanticRetryAt 向量服务失败后，在此时间 (UnixNano) 之前直接使用关键词检索，避免每次搜索都等待超时
+var semanticRetryAt atomic.Int64
+
+// embedSearchQuery 获取查询文本的向量，失败或超时返回 false
+func embedSearchQuery(query string) (pgvector.Vector, bool) {
+	embedder := GetEmbeddingService()
+	if !embedder.Enabled() {
+		return pgvector.Vector{}, false
+	}
+	cacheKey := "search:embedding:" + embedder.ModelID() + ":" + query
+	if cached := utils.GetCache().Get(cacheKey); cached != nil {
+		if vec, ok := cached.(pgvector.Vector); ok {
+			return vec, true
+		}
+	}
+
+	if time.Now().UnixNano() < semanticRetryAt.Load() {
+		return pgvector.Vector{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), semanticEmbedTimeout)
+	defer cancel()
+	embedding, err := embedder.Embed(ctx, query)
+	if err != nil {
+		log.Printf("[Search] 查询向量化失败或超时，%v 内改用关键词检索: %v", semanticRetryAfter, err)
+		semanticRetryAt.Store(time.Now().Add(semanticRetryAfter).UnixNano())
+		return pgvector.Vector{}, false
+	}
+	vec := pgvector.NewVector(embedding)
+	utils.GetCache().Set(cacheKey, vec, semanticEmbedCacheTTL)
+	return vec, true
+}
+
+// HybridSearchPosts 混合检索：关键词排名和向量相似度排名按 RRF 融合，过滤器对两路候选同样生效。
+// 没有检索词或向量服务不可用时退回 SearchPosts，第三个返回值表示是否实际使用了语义检索。
+func HybridSearchPosts(q searchquery.Query, page, perPage int) ([]models.Post, int64, bool, error) {
+	keywords := q.Keywords()
+	if keywords == "" {
+		posts, total, err := SearchPosts(q, page, perPage)
+		return posts, total, false, err
+	}
+	vec, ok := embedSearchQuery(keywords)
+	if !ok {
+		posts, total, err := SearchPosts(q, page, perPage)
+		return posts, total, false, err
+	}
+
+	// 关键词候选（检索词可能只含标点，此时只用向量候选）
+	var keywordIDs []uint
+	if utils.SearchQueryTokens(keywords) != "" {
+		relevance := q
+		relevance.Sort = searchquery.SortRelevance
+		if err := keywordSearchQuery(q).
+			Order(searchOrder(relevance)).
+			Limit(hybridCandidates).
+			Pluck("posts.id", &keywordIDs).Error; err != nil {
+			return nil, 0, true, err
+		}
+	}
+
+	// 向量候选：按余弦距离由近到远，走 embedding 上的 HNSW 索引；只比较当前模型生成的向量
+	var semanticIDs []uint
+	if err := applySearchFilters(db.DB.Model(&models.Post{}), q).
+		Where("posts.embedding IS NOT NULL AND posts.embedding_model = ?", GetEmbeddingService().ModelID()).
+		Where("1 - (posts.embedding <=> ?) > ?", vec, semanticMinSimilarity).
+		Order(gorm.Expr("posts.embedding <=> ?", vec)).
+		Limit(hybridCandidates).
+		Pluck("posts.id", &semanticIDs).Error; err != nil {
+		return nil, 0, true, err
+	}
+
+	fused := fuseRankings(keywordIDs, semanticIDs)
+	total := int64(len(fused))
+	if len(fused) == 0 {
+		return nil, 0, true, nil
+	}
+
+	// 按最新或得分排序时，融合结果只决定候选集合
+	if q.SortOrDefault() != searchquery.SortRelevance {
+		var posts []models.Post
+		err := db.DB.Model(&models.Post{}).Preload("User").Preload("Node").
+			Where("posts.id IN ?", fused).
+			Order(searchOrder(q)).
+			Limit(perPage).
+			Offset((page - 1) * perPage).
+			Find(&posts).Error
+		return posts, total, true, err
+	}
+
+	start := (page - 1) * perPage
+	if start >= len(fused) {
+		return nil, total, true, nil
+	}
+	end := start + perPage
+	if end > len(fused) {
+		end = len(fused)
+	}
+	pageIDs := fused[start:end]
+
+	var posts []models.Post
+	if err := db.DB.Preload("User").Preload("Node").Where("id IN ?", pageIDs).Find(&posts).Error; err != nil {
+		return nil, 0, true, err
+	}
+
+	// 按融合后的顺序排列
+	byID := make(map[uint]models.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	ordered := make([]models.Post, 0, len(posts))
+	for _, id := range pageIDs {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+
+	return ordered, total, true, nil
+}
+
+// fuseRankings 倒数排名融合：每个列表中排第 r 名（从 1 开始）贡献 1/(k+r)，按总分降序返回 ID
+func fuseRankings(lists ...[]uint) []uint {
+	scores := make(map[uint]float64)
+	var ids []uint
+	for _, list := range lists {
+		for i, id := range list {
+			if _, seen := scores[id]; !seen {
+				ids = append(ids, id)
+			}
+			scores[id] += 1.0 / float64(hybridRRFK+i+1)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+	"zhulink/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 讨论要点参数
+const (
+	DefaultThreadDigestMinComments = 30    // 默认展示讨论要点的最少评论数
+	threadDigestGrowthRatio        = 0.3   // 评论数比生成时增长 30% 以上才重新生成
+	threadDigestMinGrowth          = 10    // 且至少新增 10 条
+	threadDigestCommentRunes       = 300   // 每条评论最多取的字数
+	threadDigestInputRunes         = 12000 // 评论输入的总字数预算，超出时优先保留得分高的评论
+)
+
+// threadDigestFailureCooldown 生成任务重试用尽（死信）后，同一帖子在该时间内不再重新入队
+const threadDigestFailureCooldown = 6 * time.Hour
+
+// threadDigestJob 讨论要点任务的参数
+type threadDigestJob struct {
+	PostID uint `json:"post_id"`
+}
+
+// ThreadDigestMinComments 返回展示讨论要点的最少评论数（THREAD_DIGEST_MIN_COMMENTS），未配置或不合法时使用默认值
+func ThreadDigestMinComments() int {
+	if v, err := strconv.Atoi(os.Getenv("THREAD_DIGEST_MIN_COMMENTS")); err == nil && v > 0 {
+		return v
+	}
+	return DefaultThreadDigestMinComments
+}
+
+// GetThreadDigest 返回帖子已生成的讨论要点，没有时返回 nil
+func GetThreadDigest(postID uint) *models.ThreadDigest {
+	var digest models.ThreadDigest
+	if err := db.DB.Where("post_id = ?", postID).First(&digest).Error; err != nil {
+		return nil
+	}
+	return &digest
+}
+
+// ThreadDigestStale 评论数比生成时明显增长，需要重新生成
+func ThreadDigestStale(digest *models.ThreadDigest, commentCount int) bool {
+	grown := commentCount - digest.CommentCount
+	return grown >= threadDigestMinGrowth && float64(grown) >= float64(digest.CommentCount)*threadDigestGrowthRatio
+}
+
+// RequestThreadDigest 加入生成讨论要点的后台任务，同一帖子只保留一个等待中的任务。
+// 已有任务在执行，或最近一次任务失败进入死信未满冷却时间时不入队（避免模型持续出错时每次浏览都触发新的调用），
+// 返回 false 表示处于失败冷却期、暂时不会生成。
+func RequestThreadDigest(postID uint) bool {
+	key := "thread.digest:" + strconv.FormatUint(uint64(postID), 10)
+
+	var jobs []models.Job
+	db.DB.Select("status").
+		Where("dedupe_key = ? AND (status = ? OR (status = ? AND finished_at > ?))",
+			key, models.JobStatusRunning, models.JobStatusDead, time.Now().Add(-threadDigestFailureCooldown)).
+		Find(&jobs)
+	for _, job := range jobs {
+		if job.Status == models.JobStatusDead {
+			return false
+		}
+	}
+	if len(jobs) > 0 {
+		return true // 正在生成
+	}
+
+	EnqueueJob(JobThreadDigest, threadDigestJob{PostID: postID}, JobOptions{DedupeKey: key})
+	return true
+}
+
+// runThreadDigestJob 生成讨论要点；评论数不足或已有的要点仍然有效时跳过
+func runThreadDigestJob(ctx context.Context, payload []byte) error {
+	var job threadDigestJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentJobError(err)
+	}
+	return RefreshThreadDigest(ctx, job.PostID)
+}
+
+// RefreshThreadDigest 读取帖子和全部评论，生成（或重新生成）讨论要点
+func RefreshThreadDigest(ctx context.Context, postID uint) error {
+	llm := GetLLMService()
+	if !llm.Configured() {
+		return nil
+	}
+
+	var post models.Post
+	if err := db.DB.Select("id, title, content, moderation_state").First(&post, postID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 帖子已删除
+		}
+		return err
+	}
+	if post.ModerationState == models.ModerationQuarantined || post.ModerationState == models.ModerationRemoved {
+		return nil
+	}
+
+	// 与详情页相同的顺序，楼层号为按发布时间排列的序号
+	var comments []models.Comment
+	if err := db.DB.Preload("User").Where("post_id = ?", postID).Order("created_at ASC").Find(&comments).Error; err != nil {
+		return err
+	}
+	if len(comments) < ThreadDigestMinComments() {
+		return nil
+	}
+	if digest := GetThreadDigest(postID); digest != nil && !ThreadDigestStale(digest, len(comments)) {
+		return nil
+	}
+
+	content, version, err := llm.GenerateThreadDigest(ctx, post.Title, post.Content, threadDigestInput(comments))
+	if err != nil {
+		return err
+	}
+
+	digest := models.ThreadDigest{
+		PostID:        postID,
+		Content:       content,
+		CommentCount:  len(comments),
+		PromptVersion: version,
+	}
+	if err := db.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"content":        digest.Content,
+			"comment_count":  digest.CommentCount,
+			"prompt_version": digest.PromptVersion,
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&digest).Error; err != nil {
+		return err
+	}
+	log.Printf("[Digest] 已生成帖子 %d 的讨论要点（%d 条评论）", postID, len(comments))
+	return nil
+}
+
+// ThreadDigestInput 按楼层整理帖子的评论，供试运行提示词使用
+func ThreadDigestInput(postID uint) string {
+	var comments []models.Comment
+	db.DB.Preload("User").Where("post_id = ?", postID).Order("created_at ASC").Find(&comments)
+	return threadDigestInput(comments)
+}
+
+// replyFloorPattern 从回复引用（↳ 回复 [#楼层 @用户](#comment-ID)）中取出楼层号
+var replyFloorPattern = regexp.MustCompile(`\[#(\d+)`)
+
+// threadDigestInput 把评论整理为「#楼层 @用户名: 内容」，每条一行；跳过已删除、折叠和违规的评论。
+// 超出字数预算时优先保留得分高的评论，输出仍按楼层排列。
+func threadDigestInput(comments []models.Comment) string {
+	type line struct {
+		floor int
+		score int
+		text  string
+	}
+	lines := make([]line, 0, len(comments))
+	for i, comment := range comments {
+		if IsCommentDeleted(&comment) || comment.ModerationState == models.ModerationHidden || comment.ModerationState == models.ModerationRemoved {
+			continue
+		}
+		prefix, body := SplitReplyPrefix(comment.Content)
+		text := fmt.Sprintf("#%d @%s", i+1, comment.User.Username)
+		if m := replyFloorPattern.FindStringSubmatch(prefix); m != nil {
+			text += " 回复 #" + m[1]
+		}
+		body = strings.Join(strings.Fields(body), " ")
+		if len([]rune(body)) > threadDigestCommentRunes {
+			body = truncateRunes(body, threadDigestCommentRunes) + "..."
+		}
+		lines = append(lines, line{floor: i + 1, score: comment.Score, text: text + ": " + body})
+	}
+
+	// 按得分挑选，直到用完预算
+	picked := make([]line, len(lines))
+	copy(picked, lines)
+	sort.SliceStable(picked, func(a, b int) bool { return picked[a].score > picked[b].score })
+	budget := threadDigestInputRunes
+	kept := picked[:0]
+	for _, l := range picked {
+		n := len([]rune(l.text))
+		if n > budget {
+			continue
+		}
+		budget -= n
+		kept = append(kept, l)
+	}
+	sort.Slice(kept, func(a, b int) bool { return kept[a].floor < kept[b].floor })
+
+	var b strings.Builder
+	for _, l := range kept {
+		b.WriteString(l.text)
+		b.WriteString("\n")
+	}
+	if omitted := len(lines) - len(kept); omitted > 0 {
+		fmt.Fprintf(&b, "（另有 %d 条得分较低的评论因篇幅省略）\n", omitted)
+	}
+	return b.String()
+}
+
+// digestFloorPattern 讨论要点中的楼层引用 #12（不匹配 Markdown 链接和 HTML 实体中的 #）
+var digestFloorPattern = regexp.MustCompile(`(^|[^\w\[/&#])#(\d{1,5})\b`)
+
+// RenderThreadDigest 渲染讨论要点，把 #楼层 转为跳转到对应评论的链接
+func RenderThreadDigest(digest *models.ThreadDigest) template.HTML {
+	var ids []uint
+	db.DB.Model(&models.Comment{}).Where("post_id = ?", digest.PostID).Order("created_at ASC").Pluck("id", &ids)
+
+	linked := digestFloorPattern.ReplaceAllStringFunc(digest.Content, func(match string) string {
+		m := digestFloorPattern.FindStringSubmatch(match)
+		floor, _ := strconv.Atoi(m[2])
+		if floor < 1 || floor > len(ids) {
+			return match
+		}
+		return fmt.Sprintf("%s[#%d](#comment-%d)", m[1], floor, ids[floor-1])
+	})
+	return utils.RenderMarkdown(linked)
+}
+
+// GenerateThreadDigest 用启用中的 thread_digest 提示词总结评论区，同时返回所用的提示词版本
+func (s *LLMService) GenerateThreadDigest(ctx context.Context, title, content, comments string) (string, int, error) {
+	if !s.Configured() {
+		return "", 0, fmt.Errorf("LLM_TOKEN 未配置")
+	}
+
+	prompt, err := RenderPrompt(PromptThreadDigest, PromptVars{"Title": title, "Content": content, "Comments": comments})
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := s.Complete(ctx, prompt.Request())
+	if err != nil {
+		return "", prompt.Version, err
+	}
+	resp = strings.TrimSpace(resp)
+	if resp == "" {
+		return "", prompt.Version, errors.New("模型返回了空的讨论要点")
+	}
+	return resp, prompt.Version, nil
+}
+
+// threadDigestSystemPrompt 讨论要点的默认系统提示词（thread_digest 提示词第 1 版）
+const threadDigestSystemPrompt = `# Role
+你是一个中文技术社区的编辑，负责为评论很多的帖子整理「讨论要点」，帮助后来的读者快速了解大家在讨论什么。
+
+# Input
+帖子标题和正文摘要，以及按楼层排列的评论，每条一行，格式为「#楼层 @用户名: 内容」，回复其他评论时带有「回复 #楼层」。
+
+# Tasks
+1. 归纳评论区的 3-6 个主要观点、争议或有价值的补充信息（如实测数据、替代方案、踩坑经验）。
+2. 每个要点后用 #楼层 引用最有代表性的 1-3 条评论，例如：多位读者认为文档不全 #3 #17。
+3. 存在明显分歧时，分别概括各方观点。
+
+# Output Requirements
+- 使用 Markdown 无序列表，每个要点一行，不要标题、开场白和总结。
+- 只引用输入中出现过的楼层号，必须写成 #数字 的形式，不要写成"12楼"。
+- 客观转述，不加入自己的评价；不要提及用户名。
+- 使用简体中文，总字数不超过 400 字。
+`
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 投票结果
+const (
+	VoteActionCreated   = "created"   // 新投票
+	VoteActionRetracted = "retracted" // 再次点击同方向，撤回投票
+	VoteActionSwitched  = "switched"  // 反方向投票，改票
+)
+
+// VoteResult 投票操作的结果
+type VoteResult struct {
+	Action    string
+	AuthorID  uint // 内容作者
+	Upvotes   int64
+	Downvotes int64
+}
+
+// VoteMeta 投票请求的指纹信息，用于刷票分析
+type VoteMeta struct {
+	IPHash string
+	UAHash string
+}
+
+// ErrInvalidVoteItem 投票对象类型不合法
+var ErrInvalidVoteItem = errors.New("invalid vote item type")
+
+// CastVote 对帖子或评论投票（value 为 1 点赞、-1 点踩），具备切换语义：
+// 未投票则新增；再次点击同方向则撤回；点击反方向则改票。
+// 票数、Score 以及作者/投票者的积分变动在同一个事务内完成，撤回或改票时按原规则反向结算。
+func CastVote(userID uint, itemType string, itemID uint, value int, meta VoteMeta) (*VoteResult, error) {
+	column, model, err := voteTarget(itemType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VoteResult{}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// 1. 锁定目标内容，获取作者（同时串行化同一内容上的并发投票）
+		if err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("user_id").Where("id = ?", itemID).Scan(&result.AuthorID).Error; err != nil {
+			return err
+		}
+		if result.AuthorID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// 2. 查询已有投票
+		var existing models.Vote
+		found := true
+		if err := tx.Where("user_id = ? AND "+column+" = ?", userID, itemID).First(&existing).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			found = false
+		}
+
+		// 3. 根据已有投票决定新增、撤回或改票
+		var scoreDelta int
+		var settlements []voteSettlement
+		switch {
+		case !found:
+			vote := models.Vote{UserID: userID, Value: value, IPHash: meta.IPHash, UAHash: meta.UAHash}
+			if itemType == "post" {
+				vote.PostID = &itemID
+			} else {
+				vote.CommentID = &itemID
+			}
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+			result.Action = VoteActionCreated
+			scoreDelta = value
+			settlements = voteSettlements(itemType, value, userID, result.AuthorID, false)
+		case existing.Value == value:
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+			result.Action = VoteActionRetracted
+			scoreDelta = -value
+			settlements = voteSettlements(itemType, value, userID, result.AuthorID, true)
+		default:
+			if err := tx.Model(&existing).Update("value", value).Error; err != nil {
+				return err
+			}
+			result.Action = VoteActionSwitched
+			scoreDelta = value - existing.Value
+			settlements = append(voteSettlements(itemType, existing.Value, userID, result.AuthorID, true),
+				voteSettlements(itemType, value, userID, result.AuthorID, false)...)
+		}
+
+		// 4. 更新 Score（用于排序算法）
+		if err := tx.Model(model).Where("id = ?", itemID).
+			UpdateColumn("score", gorm.Expr("score + ?", scoreDelta)).Error; err != nil {
+			return err
+		}
+
+		// 5. 积分结算
+		for _, s := range settlements {
+			if err := addPointsTx(tx, s.userID, s.amount, s.action); err != nil {
+				return err
+			}
+		}
+
+		// 6. 最新票数
+		tx.Model(&models.Vote{}).Where(column+" = ? AND value = 1", itemID).Count(&result.Upvotes)
+		tx.Model(&models.Vote{}).Where(column+" = ? AND value = -1", itemID).Count(&result.Downvotes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// voteTarget 返回投票对象在 votes 表中的外键列和对应模型
+func voteTarget(itemType string) (string, interface{}, error) {
+	switch itemType {
+	case "post":
+		return "post_id", &models.Post{}, nil
+	case "comment":
+		return "comment_id", &models.Comment{}, nil
+	}
+	return "", nil, ErrInvalidVoteItem
+}
+
+// voteSettlement 一次投票引起的单笔积分变动
+type voteSettlement struct {
+	userID uint
+	amount int
+	action string
+}
+
+// voteSettlements 计算一张票带来的积分变动；reverse 为 true 时返回撤销这张票所需的反向变动
+// 规则：点赞给作者加分；点踩扣作者分，点踩者自己也扣分；给自己投票不影响作者积分
+func voteSettlements(itemType string, value int, voterID, authorID uint, reverse bool) []voteSettlement {
+	var settlements []voteSettlement
+	selfVote := voterID == authorID
+
+	if value > 0 {
+		if selfVote {
+			return nil
+		}
+		if itemType == "post" {
+			if reverse {
+				return []voteSettlement{{authorID, PointsPostUnliked, ActionPostUnliked}}
+			}
+			return []voteSettlement{{authorID, PointsPostLiked, ActionPostLiked}}
+		}
+		if reverse {
+			return []voteSettlement{{authorID, PointsCommentUnliked, ActionCommentUnliked}}
+		}
+		return []voteSettlement{{authorID, PointsCommentLiked, ActionCommentLiked}}
+	}
+
+	if !selfVote {
+		switch {
+		case itemType == "post" && reverse:
+			settlements = append(settlements, voteSettlement{authorID, PointsPostUndownvoted, ActionPostUndownvoted})
+		case itemType == "post":
+			settlements = append(settlements, voteSettlement{authorID, PointsPostDownvoted, ActionPostDownvoted})
+		case reverse:
+			settlements = append(settlements, voteSettlement{authorID, PointsCommentUndownvoted, ActionCommentUndownvoted})
+		default:
+			settlements = append(settlements, voteSettlement{authorID, PointsCommentDownvoted, ActionCommentDownvoted})
+		}
+	}
+	// 点踩者自己扣分
+	if reverse {
+		settlements = append(settlements, voteSettlement{voterID, PointsUndownvoteOther, ActionUndownvoteOther})
+	} else {
+		settlements = append(settlements, voteSettlement{voterID, PointsDownvoteOther, ActionDownvoteOther})
+	}
+	return settlements
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 刷票分析参数
+const (
+	voteAnalysisWindow        = 30 * 24 * time.Hour // 分析最近 30 天的投票
+	reciprocalMinVotes        = 5                   // 双方互相点赞均不少于 5 次才视为投票圈
+	sharedFingerprintMinUsers = 2                   // 同一指纹下至少 2 个账号
+	sharedFingerprintMinVotes = 5                   // 且组内协同投票不少于 5 次（只是共用网络的同事、室友不会被标记）
+	newAccountVoteWindow      = 5 * time.Minute     // 注册后 5 分钟内的投票视为可疑
+	newAccountMinVotes        = 3                   // 且不少于 3 票
+)
+
+// VoteAnalyzer 后台刷票分析器：定期扫描投票记录，标记可疑账号供管理员审核
+type VoteAnalyzer struct {
+	mu sync.Mutex // 防止定时任务与手动触发并发执行
+	// autoNeutralize 为 true 时，新发现的嫌疑账号的投票直接从排名计算中剔除（VOTE_RING_AUTO_NEUTRALIZE=true）
+	autoNeutralize bool
+}
+
+var (
+	voteAnalyzer     *VoteAnalyzer
+	voteAnalyzerOnce sync.Once
+)
+
+// GetVoteAnalyzer 获取刷票分析器单例
+func GetVoteAnalyzer() *VoteAnalyzer {
+	voteAnalyzerOnce.Do(func() {
+		voteAnalyzer = &VoteAnalyzer{
+			autoNeutralize: os.Getenv("VOTE_RING_AUTO_NEUTRALIZE") == "true",
+		}
+	})
+	return voteAnalyzer
+}
+
+// suspect 一次分析中发现的单个嫌疑
+type suspect struct {
+	userID   uint
+	reason   string
+	detail   string
+	related  []uint
+	evidence int
+}
+
+// Analyze 执行一次完整分析，返回本次发现的嫌疑数量
+func (a *VoteAnalyzer) Analyze() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	since := time.Now().Add(-voteAnalysisWindow)
+
+	var suspects []suspect
+	suspects = append(suspects, a.findReciprocalRings(since)...)
+	suspects = append(suspects, a.findSharedFingerprints(since)...)
+	suspects = append(suspects, a.findNewAccountVotes(since)...)
+
+	for _, s := range suspects {
+		flag, err := a.saveFlag(s)
+		if err != nil {
+			log.Printf("保存刷票嫌疑失败 (user=%d, reason=%s): %v", s.userID, s.reason, err)
+			continue
+		}
+		// 共用指纹可能只是同一网络下的正常用户，始终交由管理员审核
+		if a.autoNeutralize && flag.Status == models.VoteFlagStatusPending && flag.Reason != models.VoteFlagSharedFingerprint {
+			if _, err := NeutralizeFlag(flag); err != nil {
+				log.Printf("自动剔除刷票投票失败 (flag=%d): %v", flag.ID, err)
+			}
+		}
+	}
+	return len(suspects)
+}
+
+// findReciprocalRings 查找互相点赞的账号对（A 频繁赞 B 的内容，同时 B 频繁赞 A 的内容）
+func (a *VoteAnalyzer) findReciprocalRings(since time.Time) []suspect {
+	type pair struct {
+		UserA uint
+		UserB uint
+		AToB  int
+		BToA  int
+	}
+	var pairs []pair
+	err := db.DB.Raw(`
+		WITH va AS (
+			SELECT v.user_id AS voter, COALESCE(p.user_id, c.user_id) AS author, COUNT(*) AS n
+			FROM votes v
+			LEFT JOIN posts p ON v.post_id = p.id
+			LEFT JOIN comments c ON v.comment_id = c.id
+			WHERE v.value > 0 AND v.created_at > ?
+			GROUP BY 1, 2
+		)
+		SELECT x.voter AS user_a, x.author AS user_b, x.n AS a_to_b, y.n AS b_to_a
+		FROM va x JOIN va y ON x.voter = y.author AND x.author = y.voter
+		WHERE x.voter < x.author AND x.n >= ? AND y.n >= ?`,
+		since, reciprocalMinVotes, reciprocalMinVotes).Scan(&pairs).Error
+	if err != nil {
+		log.Printf("互赞分析失败: %v", err)
+		return nil
+	}
+
+	// 合并同一账号参与的多个互赞对，形成投票圈
+	byUser := make(map[uint]*suspect)
+	add := func(userID, other uint, votes int) {
+		s, ok := byUser[userID]
+		if !ok {
+			s = &suspect{userID: userID, reason: models.VoteFlagReciprocal}
+			byUser[userID] = s
+		}
+		s.related = append(s.related, other)
+		s.evidence += votes
+	}
+	for _, p := range pairs {
+		add(p.UserA, p.UserB, p.AToB)
+		add(p.UserB, p.UserA, p.BToA)
+	}
+
+	suspects := make([]suspect, 0, len(byUser))
+	for _, s := range byUser {
+		s.detail = fmt.Sprintf("近 30 天与 %d 个账号互相点赞，共给对方点赞 %d 次", len(s.related), s.evidence)
+		suspects = append(suspects, *s)
+	}
+	return suspects
+}
+
+// findSharedFingerprints 查找使用相同 IP + UA 指纹协同投票的多个账号。
+// 只统计组内协同的票：给同一指纹下其他账号的内容投票，或与其他账号对同一内容同向投票
+func (a *VoteAnalyzer) findSharedFingerprints(since time.Time) []suspect {
+	type group struct {
+		Users string
+		Votes int
+	}
+	var groups []group
+	err := db.DB.Raw(`
+		WITH fp AS (
+			SELECT v.ip_hash, v.ua_hash, v.user_id, v.value, v.post_id, v.comment_id,
+				COALESCE(p.user_id, c.user_id) AS author
+			FROM votes v
+			LEFT JOIN posts p ON v.post_id = p.id
+			LEFT JOIN comments c ON v.comment_id = c.id
+			WHERE v.ip_hash <> '' AND v.created_at > ?
+		),
+		coordinated AS (
+			SELECT x.ip_hash, x.ua_hash, x.user_id
+			FROM fp x
+			WHERE EXISTS (
+				SELECT 1 FROM fp y
+				WHERE y.ip_hash = x.ip_hash AND y.ua_hash = x.ua_hash AND y.user_id <> x.user_id
+				AND (y.user_id = x.author OR (y.value = x.value AND (y.post_id = x.post_id OR y.comment_id = x.comment_id)))
+			)
+		)
+		SELECT string_agg(DISTINCT user_id::text, ',') AS users, COUNT(*) AS votes
+		FROM coordinated
+		GROUP BY ip_hash, ua_hash
+		HAVING COUNT(DISTINCT user_id) >= ? AND COUNT(*) >= ?`,
+		since, sharedFingerprintMinUsers, sharedFingerprintMinVotes).Scan(&groups).Error
+	if err != nil {
+		log.Printf("指纹分析失败: %v", err)
+		return nil
+	}
+
+	byUser := make(map[uint]*suspect)
+	for _, g := range groups {
+		ids := parseUserIDs(g.Users)
+		for _, id := range ids {
+			s, ok := byUser[id]
+			if !ok {
+				s = &suspect{userID: id, reason: models.VoteFlagSharedFingerprint}
+				byUser[id] = s
+			}
+			for _, other := range ids {
+				if other != id && !containsUint(s.related, other) {
+					s.related = append(s.related, other)
+				}
+			}
+			s.evidence += g.Votes
+		}
+	}
+
+	suspects := make([]suspect, 0, len(byUser))
+	for _, s := range byUser {
+		s.detail = fmt.Sprintf("与 %d 个账号使用相同的网络和浏览器指纹，互相投票或对同一内容同向投票 %d 次", len(s.related), s.evidence)
+		suspects = append(suspects, *s)
+	}
+	return suspects
+}
+
+// findNewAccountVotes 查找注册后立即大量投票的账号
+func (a *VoteAnalyzer) findNewAccountVotes(since time.Time) []suspect {
+	type row struct {
+		UserID uint
+		Votes  int
+	}
+	var rows []row
+	err := db.DB.Raw(`
+		SELECT v.user_id, COUNT(*) AS votes
+		FROM votes v JOIN users u ON u.id = v.user_id
+		WHERE v.created_at > ? AND v.created_at < u.created_at + (? * INTERVAL '1 second')
+		GROUP BY v.user_id
+		HAVING COUNT(*) >= ?`,
+		since, int(newAccountVoteWindow.Seconds()), newAccountMinVotes).Scan(&rows).Error
+	if err != nil {
+		log.Printf("新账号投票分析失败: %v", err)
+		return nil
+	}
+
+	suspects := make([]suspect, 0, len(rows))
+	for _, r := range rows {
+		suspects = append(suspects, suspect{
+			userID:   r.UserID,
+			reason:   models.VoteFlagNewAccount,
+			detail:   fmt.Sprintf("注册后 %d 分钟内投票 %d 次", int(newAccountVoteWindow.Minutes()), r.Votes),
+			evidence: r.Votes,
+		})
+	}
+	return suspects
+}
+
+// saveFlag 新增或更新嫌疑记录；已处理的记录只刷新证据，不改变处理状态
+func (a *VoteAnalyzer) saveFlag(s suspect) (*models.VoteFlag, error) {
+	related := make([]string, 0, len(s.related))
+	for _, id := range s.related {
+		related = append(related, strconv.FormatUint(uint64(id), 10))
+	}
+	relatedStr := strings.Join(related, ",")
+	if len(relatedStr) > 500 {
+		relatedStr = relatedStr[:strings.LastIndex(relatedStr[:500], ",")]
+	}
+
+	flag := models.VoteFlag{
+		UserID:         s.userID,
+		Reason:         s.reason,
+		Detail:         s.detail,
+		RelatedUserIDs: relatedStr,
+		Evidence:       s.evidence,
+		Status:         models.VoteFlagStatusPending,
+		DetectedAt:     time.Now(),
+	}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "reason"}},
+		DoUpdates: clause.AssignmentColumns([]string{"detail", "related_user_ids", "evidence", "detected_at", "updated_at"}),
+	}).Create(&flag).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 冲突更新时 Create 不会回填状态，重新读取
+	if err := db.DB.Where("user_id = ? AND reason = ?", s.userID, s.reason).First(&flag).Error; err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// NeutralizeFlag 将嫌疑账号的相关投票从排名计算中剔除，返回受影响的票数
+// 投票圈只剔除给圈内账号的点赞；其他类型剔除分析窗口内该账号的全部投票
+func NeutralizeFlag(flag *models.VoteFlag) (int64, error) {
+	since := time.Now().Add(-voteAnalysisWindow)
+	query := db.DB.Model(&models.Vote{}).Where("user_id = ? AND created_at > ? AND neutralized = ?", flag.UserID, since, false)
+
+	switch flag.Reason {
+	case models.VoteFlagReciprocal:
+		related := parseUserIDs(flag.RelatedUserIDs)
+		if len(related) == 0 {
+			return 0, nil
+		}
+		query = query.Where("value > 0").Where(
+			db.DB.Where("post_id IN (?)", db.DB.Model(&models.Post{}).Select("id").Where("user_id IN ?", related)).
+				Or("comment_id IN (?)", db.DB.Model(&models.Comment{}).Select("id").Where("user_id IN ?", related)),
+		)
+	case models.VoteFlagNewAccount:
+		query = query.Where("created_at < (SELECT created_at FROM users WHERE id = ?) + (? * INTERVAL '1 second')",
+			flag.UserID, int(newAccountVoteWindow.Seconds()))
+	}
+
+	// 记录受影响的帖子，剔除后重新计算排名
+	query = query.Session(&gorm.Session{})
+	var postIDs []uint
+	query.Where("post_id IS NOT NULL").Distinct().Pluck("post_id", &postIDs)
+
+	result := query.Update("neutralized", true)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if err := db.DB.Model(flag).Update("status", models.VoteFlagStatusNeutralized).Error; err != nil {
+		return result.RowsAffected, err
+	}
+	flag.Status = models.VoteFlagStatusNeutralized
+
+	for _, postID := range postIDs {
+		GetRankingService().ScheduleUpdate(postID)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartScheduledAnalysis 启动定时刷票分析任务（每 6 小时执行一次）
+func (a *VoteAnalyzer) StartScheduledAnalysis(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(6 * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("刷票分析任务已停止")
+				return
+			case <-ticker.C:
+				n := a.Analyze()
+				log.Printf("刷票分析完成，发现 %d 个嫌疑", n)
+			}
+		}
+	}()
+}
+
+// parseUserIDs 解析逗号分隔的用户 ID 列表
+func parseUserIDs(s string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+func containsUint(list []uint, v uint) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
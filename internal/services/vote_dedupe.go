@@ -0,0 +1,80 @@
+package services
+
+import (
+	"zhulink/internal/db"
+	"zhulink/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DedupeVotes 清理同一用户对同一内容的重复投票（一次性迁移，见 cmd/dedupevotes）。
+// 每组只保留最早的一条；被删除的票按投票时的规则反向结算积分，
+// 评论 score 按剩余投票重算，帖子 score 扣回重复票的增量。返回删除的票数
+func DedupeVotes() (int, error) {
+	var removed []models.Vote
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`DELETE FROM votes WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY user_id, COALESCE(post_id, 0), COALESCE(comment_id, 0) ORDER BY id
+				) AS n FROM votes
+			) ranked WHERE n > 1
+		) RETURNING *`).Scan(&removed).Error; err != nil {
+			return err
+		}
+
+		postDeltas := make(map[uint]int)
+		var commentIDs []uint
+		for _, v := range removed {
+			itemType, itemID := "post", v.PostID
+			if v.PostID != nil {
+				postDeltas[*v.PostID] += v.Value
+			} else if v.CommentID != nil {
+				itemType, itemID = "comment", v.CommentID
+				commentIDs = append(commentIDs, *v.CommentID)
+			} else {
+				continue
+			}
+
+			// 撤销重复票带来的积分
+			_, model, err := voteTarget(itemType)
+			if err != nil {
+				return err
+			}
+			var authorID uint
+			if err := tx.Model(model).Select("user_id").Where("id = ?", *itemID).Scan(&authorID).Error; err != nil {
+				return err
+			}
+			if authorID == 0 {
+				continue // 内容已删除
+			}
+			for _, s := range voteSettlements(itemType, v.Value, v.UserID, authorID, true) {
+				if err := addPointsTx(tx, s.userID, s.amount, s.action); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 评论的 score 即票数之和，按剩余投票重算
+		if len(commentIDs) > 0 {
+			if err := tx.Exec(`UPDATE comments SET score = COALESCE(
+				(SELECT SUM(value) FROM votes WHERE votes.comment_id = comments.id), 0)
+				WHERE id IN ?`, commentIDs).Error; err != nil {
+				return err
+			}
+		}
+
+		// 帖子的 score 在投票时增量更新，扣回重复票的增量
+		for postID, delta := range postDeltas {
+			if err := tx.Model(&models.Post{}).Where("id = ?", postID).
+				UpdateColumn("score", gorm.Expr("score - ?", delta)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(removed), nil
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -66,3 +67,12 @@ func (c *GlobalCache) Get(key string) interface{} {
 func (c *GlobalCache) Delete(key string) {
 	c.lruCache.Remove(key)
 }
+
+// DeletePrefix 删除所有以 prefix 开头的缓存（用于失效分页列表等一组缓存）
+func (c *GlobalCache) DeletePrefix(prefix string) {
+	for _, key := range c.lruCache.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			c.lruCache.Remove(key)
+		}
+	}
+}
@@ -1,6 +1,14 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -13,3 +21,38 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+var (
+	fingerprintKey     []byte
+	fingerprintKeyOnce sync.Once
+)
+
+// fingerprintSecret 指纹哈希的密钥：FINGERPRINT_SECRET，未配置时使用 SESSION_SECRET；
+// 都未配置时使用进程内随机密钥（重启后无法与之前的指纹比对，仅适用于开发环境）
+func fingerprintSecret() []byte {
+	fingerprintKeyOnce.Do(func() {
+		secret := os.Getenv("FINGERPRINT_SECRET")
+		if secret == "" {
+			secret = os.Getenv("SESSION_SECRET")
+		}
+		if secret != "" {
+			fingerprintKey = []byte(secret)
+			return
+		}
+		log.Println("FINGERPRINT_SECRET not set; using ephemeral fingerprint key")
+		fingerprintKey = make([]byte, 32)
+		rand.Read(fingerprintKey)
+	})
+	return fingerprintKey
+}
+
+// FingerprintHash 用服务端密钥对 IP、User-Agent 等识别信息做 HMAC，便于比对又不保存原文。
+// IPv4 地址空间很小，无密钥的哈希可被穷举还原，因此密钥不能泄露
+func FingerprintHash(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, fingerprintSecret())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// previewMaxImageChecks 预览时最多检测的外链图片数量
+const previewMaxImageChecks = 10
+
+// unsupportedEmbedHosts 单独成段时不会被转换为播放器的视频站点
+var unsupportedEmbedHosts = map[string]string{
+	"vimeo.com":          "Vimeo",
+	"douyin.com":         "抖音",
+	"ixigua.com":         "西瓜视频",
+	"v.qq.com":           "腾讯视频",
+	"youku.com":          "优酷",
+	"youtube.com/shorts": "YouTube Shorts",
+}
+
+// imageCheckClient 检测图片链接用的 HTTP 客户端，拒绝连接内网地址
+var imageCheckClient = &http.Client{
+	Timeout: 3 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 3 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if isBlockedPreviewIP(net.ParseIP(host)) {
+					return fmt.Errorf("禁止访问的地址: %s", host)
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+// carrierGradeNAT 运营商级 NAT 共享地址段 (RFC 6598)，云环境中常被用作内网地址
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isBlockedPreviewIP 图片检测禁止连接的地址：回环、私有、链路本地、未指定和共享地址段
+func isBlockedPreviewIP(ip net.IP) bool {
+	return ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsUnspecified() || carrierGradeNAT.Contains(ip)
+}
+
+// RenderMarkdownPreview 使用与发布时完全相同的流程渲染草稿，并返回渲染过程中的问题提示
+// 提示包括：被忽略的内嵌 HTML、被过滤的元素与链接、不支持的视频嵌入、无法访问的图片
+func RenderMarkdownPreview(source string) (template.HTML, []string) {
+	var buf bytes.Buffer
+	if err := mdParser.Convert([]byte(source), &buf); err != nil {
+		return template.HTML(source), []string{"Markdown 解析失败，发布后将按原文显示"}
+	}
+	raw := buf.Bytes()
+	sanitized := policy.SanitizeBytes(raw)
+
+	var warnings []string
+
+	// 1. goldmark 不渲染内嵌 HTML
+	if n := bytes.Count(raw, []byte("<!-- raw HTML omitted -->")); n > 0 {
+		warnings = append(warnings, fmt.Sprintf("内嵌 HTML 不受支持，已忽略 %d 处", n))
+	}
+
+	rawDoc, err1 := goquery.NewDocumentFromReader(bytes.NewReader(raw))
+	cleanDoc, err2 := goquery.NewDocumentFromReader(bytes.NewReader(sanitized))
+	if err1 == nil && err2 == nil {
+		// 2. 安全过滤移除的元素和属性
+		warnings = append(warnings, strippedElementWarnings(rawDoc, cleanDoc)...)
+		if n := rawDoc.Find("a[href]").Length() - cleanDoc.Find("a[href]").Length(); n > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d 个链接地址不安全，链接已被移除", n))
+		}
+		if n := rawDoc.Find("img[src]").Length() - cleanDoc.Find("img[src]").Length(); n > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d 张图片地址不安全，图片已被移除", n))
+		}
+
+		// 3. 视频嵌入
+		warnings = append(warnings, embedWarnings(cleanDoc)...)
+
+		// 4. 图片链接
+		warnings = append(warnings, imageWarnings(cleanDoc)...)
+	}
+
+	return EnhanceHTMLContent(string(sanitized)), warnings
+}
+
+// strippedElementWarnings 对比过滤前后的元素数量，找出被移除的标签
+func strippedElementWarnings(rawDoc, cleanDoc *goquery.Document) []string {
+	count := func(doc *goquery.Document) map[string]int {
+		counts := make(map[string]int)
+		doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+			counts[goquery.NodeName(s)]++
+		})
+		return counts
+	}
+	before, after := count(rawDoc), count(cleanDoc)
+
+	var tags []string
+	for tag, n := range before {
+		// 链接和图片被过滤时单独提示
+		if tag == "a" || tag == "img" {
+			continue
+		}
+		if n > after[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	warnings := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		warnings = append(warnings, fmt.Sprintf("<%s> 元素不被允许，已移除 %d 个", tag, before[tag]-after[tag]))
+	}
+	return warnings
+}
+
+// embedWarnings 检查单独成段的视频链接是否能被转换为播放器（与 EnhanceHTMLContent 的规则一致）
+func embedWarnings(doc *goquery.Document) []string {
+	var warnings []string
+	doc.Find("p").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(text, "http") || strings.Contains(text, " ") {
+			return
+		}
+		if strings.Contains(text, "b23.tv/") {
+			warnings = append(warnings, "Bilibili 短链接无法嵌入播放器，请使用完整的视频链接 (bilibili.com/video/BV...)")
+			return
+		}
+		for host, name := range unsupportedEmbedHosts {
+			if strings.Contains(text, host) {
+				warnings = append(warnings, fmt.Sprintf("暂不支持嵌入%s视频，将显示为普通链接：%s", name, text))
+				return
+			}
+		}
+	})
+	return warnings
+}
+
+// imageWarnings 检查图片地址格式，并对外链图片做可访问性检测
+func imageWarnings(doc *goquery.Document) []string {
+	var warnings []string
+	var remote []string
+	doc.Find("img").Each(func(i int, s *goquery.Selection) {
+		src, exists := s.Attr("src")
+		if !exists {
+			return // 已被安全过滤移除，前面已提示
+		}
+		src = strings.TrimSpace(src)
+		switch {
+		case src == "":
+			warnings = append(warnings, "存在空的图片地址")
+		case strings.HasPrefix(src, "/"):
+			// 站内图片（如上传后的 /img/ 代理地址）不做检测
+		case strings.HasPrefix(src, "http://"):
+			warnings = append(warnings, "图片使用 http 协议，在 HTTPS 页面中可能无法显示："+src)
+			remote = append(remote, src)
+		case strings.HasPrefix(src, "https://"):
+			remote = append(remote, src)
+		default:
+			warnings = append(warnings, "图片地址无效："+src)
+		}
+	})
+
+	if len(remote) > previewMaxImageChecks {
+		remote = remote[:previewMaxImageChecks]
+	}
+
+	broken := make([]string, len(remote))
+	var wg sync.WaitGroup
+	for i, src := range remote {
+		wg.Add(1)
+		go func(i int, src string) {
+			defer wg.Done()
+			broken[i] = checkImageURL(src)
+		}(i, src)
+	}
+	wg.Wait()
+
+	for _, msg := range broken {
+		if msg != "" {
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings
+}
+
+// checkImageURL 请求图片地址，无法访问或不是图片时返回提示
+func checkImageURL(src string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, src, nil)
+	if err != nil {
+		return "图片地址无效：" + src
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; ZhuLinkPreview/1.0)")
+
+	resp, err := imageCheckClient.Do(req)
+	if err != nil {
+		return "图片无法访问：" + src
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("图片无法访问 (HTTP %d)：%s", resp.StatusCode, src)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return "链接指向的不是图片：" + src
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// 排名算法名称（同时用作列表路由和配置前缀）
+const (
+	RankerHot           = "hot"
+	RankerRising        = "rising"
+	RankerControversial = "controversial"
+	RankerBest          = "best"
+)
+
+// RankInput 排名算法的输入：帖子在某一时刻的互动统计
+type RankInput struct {
+	CreatedAt time.Time
+	Now       time.Time
+	Upvotes   float64 // 加权赞数（见 VoteWeight）
+	Downvotes float64 // 加权踩数
+	Collects  int
+	Views     int
+	Comments  int
+}
+
+// ageHours 帖子在 Now 时刻的年龄（小时）
+func (in RankInput) ageHours() float64 {
+	now := in.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return now.Sub(in.CreatedAt).Hours()
+}
+
+// Ranker 排名算法：每个实现对应一个列表页和 posts 表中的一个分数列
+type Ranker interface {
+	Name() string               // 算法名称，如 hot
+	Label() string              // 展示名称，如 热门
+	Column() string             // posts 表中持久化分数的列
+	Score(in RankInput) float64 // 计算分数，越大越靠前
+}
+
+// HotRanker 热门：对数平滑的加权互动值随时间衰减（原 CalculateWeightedScore）
+type HotRanker struct {
+	Config RankConfig
+}
+
+func (r HotRanker) Name() string   { return RankerHot }
+func (r HotRanker) Label() string  { return "热门" }
+func (r HotRanker) Column() string { return "score" }
+
+func (r HotRanker) Score(in RankInput) float64 {
+	cfg := r.Config
+	weightedSum := r.Engagement(in)
+	if weightedSum < 0 {
+		weightedSum = 0
+	}
+
+	numerator := math.Log10(weightedSum+1) * cfg.ScaleFactor
+	decay := math.Pow(in.ageHours()+cfg.TimeBase, cfg.Gravity)
+	return numerator / decay
+}
+
+// Engagement 不带时间衰减的加权互动值（热门分数的分子部分），时间窗口榜单按它排序
+func (r HotRanker) Engagement(in RankInput) float64 {
+	cfg := r.Config
+	return (in.Upvotes * cfg.WeightUpvote) +
+		(float64(in.Comments) * cfg.WeightComment) +
+		(float64(in.Collects) * cfg.WeightCollect) +
+		(float64(in.Views) * cfg.WeightView) -
+		(in.Downvotes * cfg.WeightDownvote)
+}
+
+// RisingRanker 飙升：只看最近 MaxAgeHours 内发布的帖子，按单位时间的互动速度排序
+type RisingRanker struct {
+	MaxAgeHours float64 // 超过该年龄的帖子不参与飙升榜 (48)
+	Gravity     float64 // 时间重力，越大越偏向刚发布的帖子 (1.2)
+}
+
+func (r RisingRanker) Name() string   { return RankerRising }
+func (r RisingRanker) Label() string  { return "飙升" }
+func (r RisingRanker) Column() string { return "rising_score" }
+
+func (r RisingRanker) Score(in RankInput) float64 {
+	age := in.ageHours()
+	if age > r.MaxAgeHours {
+		return 0
+	}
+	engagement := in.Upvotes - in.Downvotes + float64(in.Comments) + float64(in.Collects)
+	if engagement <= 0 {
+		return 0
+	}
+	return engagement / math.Pow(age+2, r.Gravity)
+}
+
+// ControversialRanker 争议：赞踩接近且总票数多的帖子靠前
+type ControversialRanker struct {
+	MinVotes float64 // 加权总票数低于该值时不视为有争议 (5)
+}
+
+func (r ControversialRanker) Name() string   { return RankerControversial }
+func (r ControversialRanker) Label() string  { return "争议" }
+func (r ControversialRanker) Column() string { return "controversial_score" }
+
+func (r ControversialRanker) Score(in RankInput) float64 {
+	up, down := in.Upvotes, in.Downvotes
+	if up <= 0 || down <= 0 || up+down < r.MinVotes {
+		return 0
+	}
+	balance := math.Min(up, down) / math.Max(up, down)
+	return math.Pow(up+down, balance)
+}
+
+// BestRanker 最佳（不随时间衰减）：赞数的 Wilson 置信下界，收藏视为强赞同
+type BestRanker struct {
+	Z              float64 // 置信度对应的 z 值 (1.96 ≈ 95%)
+	CollectAsVotes float64 // 一次收藏折算的赞数 (2)
+}
+
+func (r BestRanker) Name() string   { return RankerBest }
+func (r BestRanker) Label() string  { return "最佳" }
+func (r BestRanker) Column() string { return "best_score" }
+
+func (r BestRanker) Score(in RankInput) float64 {
+	up := in.Upvotes + float64(in.Collects)*r.CollectAsVotes
+	n := up + in.Downvotes
+	if n <= 0 {
+		return 0
+	}
+	z := r.Z
+	phat := up / n
+	return (phat + z*z/(2*n) - z*math.Sqrt((phat*(1-phat)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// LoadHotConfig 当前生效的热门算法参数：DefaultConfig 叠加 RANK_HOT_<参数> 环境变量
+func LoadHotConfig() RankConfig {
+	hot := DefaultConfig
+	hot.Gravity = envFloat("RANK_HOT_GRAVITY", hot.Gravity)
+	hot.WeightCollect = envFloat("RANK_HOT_WEIGHT_COLLECT", hot.WeightCollect)
+	hot.WeightComment = envFloat("RANK_HOT_WEIGHT_COMMENT", hot.WeightComment)
+	hot.WeightUpvote = envFloat("RANK_HOT_WEIGHT_UPVOTE", hot.WeightUpvote)
+	hot.WeightDownvote = envFloat("RANK_HOT_WEIGHT_DOWNVOTE", hot.WeightDownvote)
+	hot.WeightView = envFloat("RANK_HOT_WEIGHT_VIEW", hot.WeightView)
+	hot.ScaleFactor = envFloat("RANK_HOT_SCALE_FACTOR", hot.ScaleFactor)
+	hot.TimeBase = envFloat("RANK_HOT_TIME_BASE", hot.TimeBase)
+	return hot
+}
+
+// LoadRankers 按默认参数创建全部排名算法，参数可用环境变量 RANK_<算法>_<参数> 覆盖，
+// 如 RANK_HOT_GRAVITY=1.8、RANK_RISING_MAX_AGE_HOURS=24
+func LoadRankers() []Ranker {
+	return []Ranker{
+		HotRanker{Config: LoadHotConfig()},
+		RisingRanker{
+			MaxAgeHours: envFloat("RANK_RISING_MAX_AGE_HOURS", 48),
+			Gravity:     envFloat("RANK_RISING_GRAVITY", 1.2),
+		},
+		ControversialRanker{
+			MinVotes: envFloat("RANK_CONTROVERSIAL_MIN_VOTES", 5),
+		},
+		BestRanker{
+			Z:              envFloat("RANK_BEST_Z", 1.96),
+			CollectAsVotes: envFloat("RANK_BEST_COLLECT_AS_VOTES", 2),
+		},
+	}
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"math"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,32 +30,101 @@ var DefaultConfig = RankConfig{
 	TimeBase:       24.0,   // 时间基数,防止新帖分数虚高
 }
 
-func CalculateScore(t time.Time, up, down, collect, view, comment int) float64 {
-	hours := time.Since(t).Hours()
-
-	// 1. 计算加权互动值 (Weighted Sum)
-	// 浏览量以极小权重参与计算,避免数量级过大扭曲结果
-	weightedSum := (float64(up) * DefaultConfig.WeightUpvote) +
-		(float64(comment) * DefaultConfig.WeightComment) +
-		(float64(collect) * DefaultConfig.WeightCollect) +
-		(float64(view) * DefaultConfig.WeightView) -
-		(float64(down) * DefaultConfig.WeightDownvote)
-
-	// 2. 基础修正
-	if weightedSum < 0 {
-		weightedSum = 0 // 防止负数无法取对数
+// LevelVoteWeights 各等级用户的投票在排名中的权重（按 GetUserLevel 的等级名称配置），
+// 可通过 RANK_VOTE_WEIGHTS 覆盖，见 LoadVoteWeights
+var LevelVoteWeights = map[string]float64{
+	"萌芽": 0.3,
+	"破土": 0.6,
+	"新竹": 1.0,
+	"翠竹": 1.2,
+	"成林": 1.5,
+}
+
+// 新账号投票折扣：注册不满 NewAccountVoteDays 天的账号，投票权重再乘以 NewAccountVoteFactor
+var (
+	NewAccountVoteDays   = 7
+	NewAccountVoteFactor = 0.5
+)
+
+// LoadVoteWeights 从环境变量覆盖投票权重（启动时调用一次），未配置或不合法的项保持默认值：
+// RANK_VOTE_WEIGHTS="萌芽:0.3,成林:1.5" 只覆盖列出的等级，RANK_NEW_ACCOUNT_VOTE_DAYS、RANK_NEW_ACCOUNT_VOTE_FACTOR 配置新账号折扣
+func LoadVoteWeights() {
+	for level, value := range ParseLevelMap(os.Getenv("RANK_VOTE_WEIGHTS")) {
+		if w, err := strconv.ParseFloat(value, 64); err == nil && w >= 0 {
+			LevelVoteWeights[level] = w
+		}
+	}
+	NewAccountVoteDays = int(envFloat("RANK_NEW_ACCOUNT_VOTE_DAYS", float64(NewAccountVoteDays)))
+	NewAccountVoteFactor = envFloat("RANK_NEW_ACCOUNT_VOTE_FACTOR", NewAccountVoteFactor)
+}
+
+// envFloat 读取浮点型环境变量，未配置或不合法时返回默认值
+func envFloat(key string, def float64) float64 {
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(key)), 64); err == nil {
+		return v
+	}
+	return def
+}
+
+// VoteWeight 根据投票者的积分等级和账号年龄计算单张票的排名权重
+func VoteWeight(points int, accountCreatedAt time.Time) float64 {
+	return VoteWeightAt(points, accountCreatedAt, time.Now())
+}
+
+// VoteWeightAt 按 at 时刻的账号年龄计算投票权重（用于回放历史投票）
+func VoteWeightAt(points int, accountCreatedAt, at time.Time) float64 {
+	levelName, _ := GetUserLevel(points)
+	weight, ok := LevelVoteWeights[levelName]
+	if !ok {
+		weight = 1.0
+	}
+	if int(at.Sub(accountCreatedAt).Hours()/24) < NewAccountVoteDays {
+		weight *= NewAccountVoteFactor
 	}
+	return weight
+}
+
+// CalculateScore 按原始票数计算帖子分数（每票权重为 1）
+func CalculateScore(t time.Time, up, down, collect, view, comment int) float64 {
+	return CalculateWeightedScore(t, float64(up), float64(down), collect, view, comment)
+}
 
-	// 3. 对数平滑 (Log Smoothing)
-	// log10(sum + 1) -> 确保 sum=0 时结果为 0
-	logScore := math.Log10(weightedSum + 1)
+// CalculateWeightedScore 按加权票数计算帖子热门分数（DefaultConfig 下的 HotRanker）
+// up/down 为按 VoteWeight 累加后的赞/踩权重之和
+func CalculateWeightedScore(t time.Time, up, down float64, collect, view, comment int) float64 {
+	return HotRanker{Config: DefaultConfig}.Score(RankInput{
+		CreatedAt: t,
+		Upvotes:   up,
+		Downvotes: down,
+		Collects:  collect,
+		Views:     view,
+		Comments:  comment,
+	})
+}
+
+// WilsonLowerBound 计算赞/踩的 Wilson 置信区间下界（95% 置信度）
+// 用于"最佳"排序：票数少时更保守，避免一两个赞就排到最前
+func WilsonLowerBound(up, down int) float64 {
+	n := float64(up + down)
+	if n == 0 {
+		return 0
+	}
 
-	// 4. 放大系数 (0.x -> 几十)
-	numerator := logScore * DefaultConfig.ScaleFactor
+	const z = 1.96
+	phat := float64(up) / n
+	return (phat + z*z/(2*n) - z*math.Sqrt((phat*(1-phat)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
 
-	// 5. 时间衰减 (分母)
-	// 使用时间基数防止新帖子分数虚高
-	decay := math.Pow(hours+DefaultConfig.TimeBase, DefaultConfig.Gravity)
+// ControversyScore 计算争议度：赞踩越接近、总票数越多，争议度越高
+func ControversyScore(up, down int) float64 {
+	if up <= 0 || down <= 0 {
+		return 0
+	}
 
-	return numerator / decay
+	magnitude := float64(up + down)
+	balance := float64(down) / float64(up)
+	if up < down {
+		balance = float64(up) / float64(down)
+	}
+	return math.Pow(magnitude, balance)
 }
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// 全文检索分词
+//
+// Postgres 自带的分词器不认识中文，这里在 Go 侧完成分词再交给 to_tsvector('simple', ...)：
+//   - 拉丁字母和数字按连续片段切成单词并转小写
+//   - 汉字等 CJK 文字按二元组（bigram）切分，文档额外保留单字，以便单字查询也能命中
+//   - CJK 词元编码为 "c" + 码点十六进制，保证在任何数据库 locale 下都被解析为单个 ASCII 词
+//
+// 文档和查询必须使用同一套规则编码。
+
+// isCJK 判断是否为按二元组切分的字符（汉字、假名、谚文）
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// searchRun 文本中的一个连续片段（拉丁单词或 CJK 串）
+type searchRun struct {
+	text string
+	cjk  bool
+}
+
+// splitSearchRuns 把文本切分为拉丁单词和 CJK 串，标点和空白作为分隔
+func splitSearchRuns(text string) []searchRun {
+	var runs []searchRun
+	var buf []rune
+	cjk := false
+	flush := func() {
+		if len(buf) > 0 {
+			runs = append(runs, searchRun{text: string(buf), cjk: cjk})
+			buf = buf[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+				cjk = true
+			}
+			buf = append(buf, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+				cjk = false
+			}
+			buf = append(buf, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
+
+// encodeCJKToken 将 CJK 词元编码为纯 ASCII
+func encodeCJKToken(runes []rune) string {
+	var b strings.Builder
+	b.WriteByte('c')
+	for _, r := range runes {
+		fmt.Fprintf(&b, "%x", r)
+	}
+	return b.String()
+}
+
+// SearchDocumentTokens 生成文档的检索词元（空格分隔），用于 to_tsvector('simple', ...)
+func SearchDocumentTokens(text string) string {
+	var tokens []string
+	for _, run := range splitSearchRuns(text) {
+		if !run.cjk {
+			tokens = append(tokens, run.text)
+			continue
+		}
+		runes := []rune(run.text)
+		for i := range runes {
+			tokens = append(tokens, encodeCJKToken(runes[i:i+1]))
+			if i+1 < len(runes) {
+				tokens = append(tokens, encodeCJKToken(runes[i:i+2]))
+			}
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// SearchQueryTokens 生成查询的检索词元（空格分隔），用于 plainto_tsquery('simple', ...)
+// 多字 CJK 串只用二元组匹配，单字才用单字匹配，避免单字匹配带来大量噪音
+func SearchQueryTokens(query string) string {
+	var tokens []string
+	for _, run := range splitSearchRuns(query) {
+		if !run.cjk {
+			tokens = append(tokens, run.text)
+			continue
+		}
+		runes := []rune(run.text)
+		if len(runes) == 1 {
+			tokens = append(tokens, encodeCJKToken(runes))
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			tokens = append(tokens, encodeCJKToken(runes[i:i+2]))
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// SearchTerms 返回查询中的原始词（拉丁单词和 CJK 串），用于结果高亮
+func SearchTerms(query string) []string {
+	runs := splitSearchRuns(query)
+	terms := make([]string, 0, len(runs))
+	for _, run := range runs {
+		terms = append(terms, run.text)
+	}
+	return terms
+}
+
+// EscapeLike 转义 LIKE/ILIKE 模式中的通配符，用于按字面匹配用户输入
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// HighlightSnippet 从 text 中截取包含首个命中词的片段（最多 maxRunes 个字符），
+// 并用 <mark> 标记所有命中词；未命中时返回开头部分。输出已转义，可直接嵌入页面。
+func HighlightSnippet(text string, terms []string, maxRunes int) template.HTML {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := []rune(strings.ToLower(string(runes)))
+
+	// 定位首个命中位置，片段从其前方约 1/4 处开始
+	start := 0
+	if first := indexAnyTerm(lower, terms, 0); first >= 0 {
+		start = first - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + maxRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		matchLen := matchTermAt(lower, terms, i)
+		if matchLen == 0 {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		stop := i + matchLen
+		if stop > end {
+			stop = end
+		}
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[i:stop])))
+		b.WriteString("</mark>")
+		i = stop
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return template.HTML(b.String())
+}
+
+// indexAnyTerm 返回从 from 开始首个命中任意词的位置，未命中返回 -1
+func indexAnyTerm(lower []rune, terms []string, from int) int {
+	for i := from; i < len(lower); i++ {
+		if matchTermAt(lower, terms, i) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchTermAt 返回位置 i 处命中的最长词的长度（字符数），未命中返回 0
+func matchTermAt(lower []rune, terms []string, i int) int {
+	best := 0
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) <= best || i+len(t) > len(lower) {
+			continue
+		}
+		if string(lower[i:i+len(t)]) == term {
+			best = len(t)
+		}
+	}
+	return best
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -21,6 +22,20 @@ func GetUserLevel(points int) (name string, icon string) {
 	}
 }
 
+// ParseLevelMap 解析按等级配置的环境变量，格式 "萌芽:5m,破土:10m"，返回等级名称到值的映射
+func ParseLevelMap(s string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		level, value, ok := strings.Cut(item, ":")
+		level, value = strings.TrimSpace(level), strings.TrimSpace(value)
+		if !ok || level == "" || value == "" {
+			continue
+		}
+		result[level] = value
+	}
+	return result
+}
+
 // GetDaysSinceJoined 计算入林天数
 func GetDaysSinceJoined(createdAt time.Time) int {
 	return int(time.Since(createdAt).Hours() / 24)
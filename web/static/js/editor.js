@@ -9,23 +9,33 @@ function markdownEditor() {
         isFullScreen: false,
         isSplitView: false,
         isUploading: false, // 图片上传状态
+        previewHTML: '', // 服务端渲染的预览结果
+        previewTimer: null,
 
         init() {
             // DOM-Bridge: Sync initial content from textarea value
             this.content = this.$refs.input.value;
             this.autoResize(this.$refs.input);
+            // 对照模式下输入时自动刷新预览
+            this.$watch('content', () => {
+                if (this.isSplitView) this.refreshPreview(400);
+            });
             console.log("Markdown Editor Initialized");
         },
 
         togglePreview() {
             this.isPreview = !this.isPreview;
-            if (this.isPreview) this.isSplitView = false; // Disable split view if switching to full preview
+            if (this.isPreview) {
+                this.isSplitView = false; // Disable split view if switching to full preview
+                this.refreshPreview(0);
+            }
         },
 
         toggleSplitView() {
             this.isSplitView = !this.isSplitView;
             if (this.isSplitView) {
                 this.isPreview = false; // Ensure we are not in full preview mode
+                this.refreshPreview(0);
                 // Force creating icons after layout change
                 this.$nextTick(() => {
                     this.autoResize(this.$refs.input);
@@ -33,6 +43,29 @@ function markdownEditor() {
             }
         },
 
+        // 请求服务端预览（与发布后完全一致的渲染流程，并附带问题提示）
+        // 请求失败（如未登录或频率受限）时退回浏览器端渲染
+        refreshPreview(delay) {
+            clearTimeout(this.previewTimer);
+            this.previewTimer = setTimeout(async () => {
+                const text = this.content;
+                if (!text) {
+                    this.previewHTML = this.renderMarkdown(text);
+                    return;
+                }
+                try {
+                    const formData = new FormData();
+                    formData.append('content', text);
+                    const response = await fetch('/api/preview', { method: 'POST', body: formData });
+                    if (!response.ok || response.redirected) throw new Error('preview failed: ' + response.status);
+                    this.previewHTML = await response.text();
+                } catch (e) {
+                    console.error("Server preview error:", e);
+                    this.previewHTML = this.renderMarkdown(text);
+                }
+            }, delay);
+        },
+
         toggleFullScreen() {
             this.isFullScreen = !this.isFullScreen;
             if (!this.isFullScreen) {